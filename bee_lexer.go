@@ -0,0 +1,2664 @@
+// Code generated by SyntaxMap.Generate. DO NOT EDIT.
+
+package main
+
+// BeeLexer is a Lexer matching the patterns of a SyntaxMap in order
+func BeeLexer(src string, index int, longest bool, eof bool) (Trait, int, bool) {
+	trait, length := None, -1
+	for _, pt := range beeLexerPatterns {
+		match, more := pt.match(src[index:], eof)
+		if more {
+			return None, -1, true
+		}
+		if match > length {
+			trait, length = pt.trait, match
+			if !longest {
+				break
+			}
+		}
+	}
+	return trait, length, false
+}
+
+var beeLexerPatterns = []struct {
+	trait Trait
+	match func(string, bool) (int, bool)
+}{
+	{NewLine, beeLexer0},
+	{Blank, beeLexer1},
+	{Comment, beeLexer2},
+	{Comment, beeLexer3},
+	{UnterminatedComment, beeLexer4},
+	{Directive, beeLexer5},
+	{KwStruct, beeLexer6},
+	{KwEnum, beeLexer7},
+	{KwUnion, beeLexer8},
+	{KwUnderscore, beeLexer9},
+	{KwSelf, beeLexer10},
+	{KwBreak, beeLexer11},
+	{KwCase, beeLexer12},
+	{KwContinue, beeLexer13},
+	{KwElse, beeLexer14},
+	{KwEach, beeLexer15},
+	{KwFor, beeLexer16},
+	{KwIf, beeLexer17},
+	{KwReturn, beeLexer18},
+	{KwSwitch, beeLexer19},
+	{KwAnd, beeLexer20},
+	{KwOr, beeLexer21},
+	{KwFn, beeLexer22},
+	{ParenBegin, beeLexer23},
+	{ParenEnd, beeLexer24},
+	{ScopeBegin, beeLexer25},
+	{ScopeEnd, beeLexer26},
+	{CrochetBegin, beeLexer27},
+	{CrochetEnd, beeLexer28},
+	{Arrow, beeLexer29},
+	{Increment, beeLexer30},
+	{Decrement, beeLexer31},
+	{Add, beeLexer32},
+	{Sub, beeLexer33},
+	{MalformedNumber, beeLexer34},
+	{IntBin, beeLexer35},
+	{IntHex, beeLexer36},
+	{MalformedNumber, beeLexer37},
+	{Float, beeLexer38},
+	{IntDec, beeLexer39},
+	{RawStr, beeLexer40},
+	{UnterminatedStr, beeLexer41},
+	{Str, beeLexer42},
+	{UnterminatedStr, beeLexer43},
+	{Char, beeLexer44},
+	{UnterminatedChar, beeLexer45},
+	{Identifier, beeLexer46},
+	{Declare, beeLexer47},
+	{Define, beeLexer48},
+	{BinNot, beeLexer49},
+	{BinOr, beeLexer50},
+	{BinXor, beeLexer51},
+	{BinShiftL, beeLexer52},
+	{BinShiftR, beeLexer53},
+	{Div, beeLexer54},
+	{Mod, beeLexer55},
+	{Equal, beeLexer56},
+	{NotEq, beeLexer57},
+	{LessEq, beeLexer58},
+	{GreaterEq, beeLexer59},
+	{Less, beeLexer60},
+	{Greater, beeLexer61},
+	{Not, beeLexer62},
+	{Assign, beeLexer63},
+	{Ref, beeLexer64},
+	{Deref, beeLexer65},
+	{Dot, beeLexer66},
+	{Comma, beeLexer67},
+	{Semicolon, beeLexer68},
+}
+
+// '\n'
+func beeLexer0(src string, eof bool) (int, bool) {
+	match, state := -1, 0
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch state {
+		case 0:
+			switch {
+			case c == '\n':
+				state = 1
+			default:
+				return match, false
+			}
+		case 1:
+			return i, false
+		}
+	}
+	if !eof {
+		return match, true
+	}
+	switch state {
+	case 1:
+		return len(src), false
+	}
+	return match, false
+}
+
+// {/!'\n' _}+
+func beeLexer1(src string, eof bool) (int, bool) {
+	match, state := -1, 0
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch state {
+		case 0:
+			switch {
+			case 0x08 <= c && c <= '\t', 0x0b <= c && c <= 0x0c, c == ' ':
+				state = 1
+			default:
+				return match, false
+			}
+		case 1:
+			switch {
+			case 0x08 <= c && c <= '\t', 0x0b <= c && c <= 0x0c, c == ' ':
+				match, state = i, 1
+			default:
+				return i, false
+			}
+		}
+	}
+	if !eof {
+		return match, true
+	}
+	switch state {
+	case 1:
+		return len(src), false
+	}
+	return match, false
+}
+
+// '//' {!'\n'}*
+func beeLexer2(src string, eof bool) (int, bool) {
+	match, state := -1, 0
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch state {
+		case 0:
+			switch {
+			case c == '/':
+				state = 1
+			default:
+				return match, false
+			}
+		case 1:
+			switch {
+			case c == '/':
+				state = 2
+			default:
+				return match, false
+			}
+		case 2:
+			switch {
+			case c == '\n':
+				return i, false
+			default:
+				match, state = i, 2
+			}
+		}
+	}
+	if !eof {
+		return match, true
+	}
+	switch state {
+	case 2:
+		return len(src), false
+	}
+	return match, false
+}
+
+// /* */
+func beeLexer3(src string, eof bool) (int, bool) {
+	return Nesting{"/*", "*/"}.MatchMore(src, 0, eof)
+}
+
+// '/*' ^*
+func beeLexer4(src string, eof bool) (int, bool) {
+	match, state := -1, 0
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch state {
+		case 0:
+			switch {
+			case c == '/':
+				state = 1
+			default:
+				return match, false
+			}
+		case 1:
+			switch {
+			case c == '*':
+				state = 2
+			default:
+				return match, false
+			}
+		case 2:
+			match, state = i, 2
+		}
+	}
+	if !eof {
+		return match, true
+	}
+	switch state {
+	case 2:
+		return len(src), false
+	}
+	return match, false
+}
+
+// '#' <:idhead> {<:idhead>|n}*
+func beeLexer5(src string, eof bool) (int, bool) {
+	match, state := -1, 0
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch state {
+		case 0:
+			switch {
+			case c == '#':
+				state = 1
+			default:
+				return match, false
+			}
+		case 1:
+			switch {
+			case 'A' <= c && c <= 'Z', c == '_', 'a' <= c && c <= 'z':
+				state = 2
+			default:
+				return match, false
+			}
+		case 2:
+			switch {
+			case '0' <= c && c <= '9', 'A' <= c && c <= 'Z', c == '_', 'a' <= c && c <= 'z':
+				match, state = i, 2
+			default:
+				return i, false
+			}
+		}
+	}
+	if !eof {
+		return match, true
+	}
+	switch state {
+	case 2:
+		return len(src), false
+	}
+	return match, false
+}
+
+// 'struct'/!a
+func beeLexer6(src string, eof bool) (int, bool) {
+	match, state := -1, 0
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch state {
+		case 0:
+			switch {
+			case c == 's':
+				state = 1
+			default:
+				return match, false
+			}
+		case 1:
+			switch {
+			case c == 't':
+				state = 2
+			default:
+				return match, false
+			}
+		case 2:
+			switch {
+			case c == 'r':
+				state = 3
+			default:
+				return match, false
+			}
+		case 3:
+			switch {
+			case c == 'u':
+				state = 4
+			default:
+				return match, false
+			}
+		case 4:
+			switch {
+			case c == 'c':
+				state = 5
+			default:
+				return match, false
+			}
+		case 5:
+			switch {
+			case c == 't':
+				state = 6
+			default:
+				return match, false
+			}
+		case 6:
+			switch {
+			case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z':
+				return match, false
+			default:
+				return i, false
+			}
+		}
+	}
+	if !eof {
+		return match, true
+	}
+	return match, false
+}
+
+// 'enum'/!a
+func beeLexer7(src string, eof bool) (int, bool) {
+	match, state := -1, 0
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch state {
+		case 0:
+			switch {
+			case c == 'e':
+				state = 1
+			default:
+				return match, false
+			}
+		case 1:
+			switch {
+			case c == 'n':
+				state = 2
+			default:
+				return match, false
+			}
+		case 2:
+			switch {
+			case c == 'u':
+				state = 3
+			default:
+				return match, false
+			}
+		case 3:
+			switch {
+			case c == 'm':
+				state = 4
+			default:
+				return match, false
+			}
+		case 4:
+			switch {
+			case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z':
+				return match, false
+			default:
+				return i, false
+			}
+		}
+	}
+	if !eof {
+		return match, true
+	}
+	return match, false
+}
+
+// 'union'/!a
+func beeLexer8(src string, eof bool) (int, bool) {
+	match, state := -1, 0
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch state {
+		case 0:
+			switch {
+			case c == 'u':
+				state = 1
+			default:
+				return match, false
+			}
+		case 1:
+			switch {
+			case c == 'n':
+				state = 2
+			default:
+				return match, false
+			}
+		case 2:
+			switch {
+			case c == 'i':
+				state = 3
+			default:
+				return match, false
+			}
+		case 3:
+			switch {
+			case c == 'o':
+				state = 4
+			default:
+				return match, false
+			}
+		case 4:
+			switch {
+			case c == 'n':
+				state = 5
+			default:
+				return match, false
+			}
+		case 5:
+			switch {
+			case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z':
+				return match, false
+			default:
+				return i, false
+			}
+		}
+	}
+	if !eof {
+		return match, true
+	}
+	return match, false
+}
+
+// '_'/!a
+func beeLexer9(src string, eof bool) (int, bool) {
+	match, state := -1, 0
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch state {
+		case 0:
+			switch {
+			case c == '_':
+				state = 1
+			default:
+				return match, false
+			}
+		case 1:
+			switch {
+			case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z':
+				return match, false
+			default:
+				return i, false
+			}
+		}
+	}
+	if !eof {
+		return match, true
+	}
+	return match, false
+}
+
+// '$'
+func beeLexer10(src string, eof bool) (int, bool) {
+	match, state := -1, 0
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch state {
+		case 0:
+			switch {
+			case c == '$':
+				state = 1
+			default:
+				return match, false
+			}
+		case 1:
+			return i, false
+		}
+	}
+	if !eof {
+		return match, true
+	}
+	switch state {
+	case 1:
+		return len(src), false
+	}
+	return match, false
+}
+
+// 'break'/!a
+func beeLexer11(src string, eof bool) (int, bool) {
+	match, state := -1, 0
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch state {
+		case 0:
+			switch {
+			case c == 'b':
+				state = 1
+			default:
+				return match, false
+			}
+		case 1:
+			switch {
+			case c == 'r':
+				state = 2
+			default:
+				return match, false
+			}
+		case 2:
+			switch {
+			case c == 'e':
+				state = 3
+			default:
+				return match, false
+			}
+		case 3:
+			switch {
+			case c == 'a':
+				state = 4
+			default:
+				return match, false
+			}
+		case 4:
+			switch {
+			case c == 'k':
+				state = 5
+			default:
+				return match, false
+			}
+		case 5:
+			switch {
+			case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z':
+				return match, false
+			default:
+				return i, false
+			}
+		}
+	}
+	if !eof {
+		return match, true
+	}
+	return match, false
+}
+
+// 'case'/!a
+func beeLexer12(src string, eof bool) (int, bool) {
+	match, state := -1, 0
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch state {
+		case 0:
+			switch {
+			case c == 'c':
+				state = 1
+			default:
+				return match, false
+			}
+		case 1:
+			switch {
+			case c == 'a':
+				state = 2
+			default:
+				return match, false
+			}
+		case 2:
+			switch {
+			case c == 's':
+				state = 3
+			default:
+				return match, false
+			}
+		case 3:
+			switch {
+			case c == 'e':
+				state = 4
+			default:
+				return match, false
+			}
+		case 4:
+			switch {
+			case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z':
+				return match, false
+			default:
+				return i, false
+			}
+		}
+	}
+	if !eof {
+		return match, true
+	}
+	return match, false
+}
+
+// 'continue'/!a
+func beeLexer13(src string, eof bool) (int, bool) {
+	match, state := -1, 0
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch state {
+		case 0:
+			switch {
+			case c == 'c':
+				state = 1
+			default:
+				return match, false
+			}
+		case 1:
+			switch {
+			case c == 'o':
+				state = 2
+			default:
+				return match, false
+			}
+		case 2:
+			switch {
+			case c == 'n':
+				state = 3
+			default:
+				return match, false
+			}
+		case 3:
+			switch {
+			case c == 't':
+				state = 4
+			default:
+				return match, false
+			}
+		case 4:
+			switch {
+			case c == 'i':
+				state = 5
+			default:
+				return match, false
+			}
+		case 5:
+			switch {
+			case c == 'n':
+				state = 6
+			default:
+				return match, false
+			}
+		case 6:
+			switch {
+			case c == 'u':
+				state = 7
+			default:
+				return match, false
+			}
+		case 7:
+			switch {
+			case c == 'e':
+				state = 8
+			default:
+				return match, false
+			}
+		case 8:
+			switch {
+			case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z':
+				return match, false
+			default:
+				return i, false
+			}
+		}
+	}
+	if !eof {
+		return match, true
+	}
+	return match, false
+}
+
+// 'else'/!a
+func beeLexer14(src string, eof bool) (int, bool) {
+	match, state := -1, 0
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch state {
+		case 0:
+			switch {
+			case c == 'e':
+				state = 1
+			default:
+				return match, false
+			}
+		case 1:
+			switch {
+			case c == 'l':
+				state = 2
+			default:
+				return match, false
+			}
+		case 2:
+			switch {
+			case c == 's':
+				state = 3
+			default:
+				return match, false
+			}
+		case 3:
+			switch {
+			case c == 'e':
+				state = 4
+			default:
+				return match, false
+			}
+		case 4:
+			switch {
+			case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z':
+				return match, false
+			default:
+				return i, false
+			}
+		}
+	}
+	if !eof {
+		return match, true
+	}
+	return match, false
+}
+
+// 'each'/!a
+func beeLexer15(src string, eof bool) (int, bool) {
+	match, state := -1, 0
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch state {
+		case 0:
+			switch {
+			case c == 'e':
+				state = 1
+			default:
+				return match, false
+			}
+		case 1:
+			switch {
+			case c == 'a':
+				state = 2
+			default:
+				return match, false
+			}
+		case 2:
+			switch {
+			case c == 'c':
+				state = 3
+			default:
+				return match, false
+			}
+		case 3:
+			switch {
+			case c == 'h':
+				state = 4
+			default:
+				return match, false
+			}
+		case 4:
+			switch {
+			case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z':
+				return match, false
+			default:
+				return i, false
+			}
+		}
+	}
+	if !eof {
+		return match, true
+	}
+	return match, false
+}
+
+// 'for'/!a
+func beeLexer16(src string, eof bool) (int, bool) {
+	match, state := -1, 0
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch state {
+		case 0:
+			switch {
+			case c == 'f':
+				state = 1
+			default:
+				return match, false
+			}
+		case 1:
+			switch {
+			case c == 'o':
+				state = 2
+			default:
+				return match, false
+			}
+		case 2:
+			switch {
+			case c == 'r':
+				state = 3
+			default:
+				return match, false
+			}
+		case 3:
+			switch {
+			case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z':
+				return match, false
+			default:
+				return i, false
+			}
+		}
+	}
+	if !eof {
+		return match, true
+	}
+	return match, false
+}
+
+// 'if'/!a
+func beeLexer17(src string, eof bool) (int, bool) {
+	match, state := -1, 0
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch state {
+		case 0:
+			switch {
+			case c == 'i':
+				state = 1
+			default:
+				return match, false
+			}
+		case 1:
+			switch {
+			case c == 'f':
+				state = 2
+			default:
+				return match, false
+			}
+		case 2:
+			switch {
+			case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z':
+				return match, false
+			default:
+				return i, false
+			}
+		}
+	}
+	if !eof {
+		return match, true
+	}
+	return match, false
+}
+
+// 'return'/!a
+func beeLexer18(src string, eof bool) (int, bool) {
+	match, state := -1, 0
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch state {
+		case 0:
+			switch {
+			case c == 'r':
+				state = 1
+			default:
+				return match, false
+			}
+		case 1:
+			switch {
+			case c == 'e':
+				state = 2
+			default:
+				return match, false
+			}
+		case 2:
+			switch {
+			case c == 't':
+				state = 3
+			default:
+				return match, false
+			}
+		case 3:
+			switch {
+			case c == 'u':
+				state = 4
+			default:
+				return match, false
+			}
+		case 4:
+			switch {
+			case c == 'r':
+				state = 5
+			default:
+				return match, false
+			}
+		case 5:
+			switch {
+			case c == 'n':
+				state = 6
+			default:
+				return match, false
+			}
+		case 6:
+			switch {
+			case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z':
+				return match, false
+			default:
+				return i, false
+			}
+		}
+	}
+	if !eof {
+		return match, true
+	}
+	return match, false
+}
+
+// 'switch'/!a
+func beeLexer19(src string, eof bool) (int, bool) {
+	match, state := -1, 0
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch state {
+		case 0:
+			switch {
+			case c == 's':
+				state = 1
+			default:
+				return match, false
+			}
+		case 1:
+			switch {
+			case c == 'w':
+				state = 2
+			default:
+				return match, false
+			}
+		case 2:
+			switch {
+			case c == 'i':
+				state = 3
+			default:
+				return match, false
+			}
+		case 3:
+			switch {
+			case c == 't':
+				state = 4
+			default:
+				return match, false
+			}
+		case 4:
+			switch {
+			case c == 'c':
+				state = 5
+			default:
+				return match, false
+			}
+		case 5:
+			switch {
+			case c == 'h':
+				state = 6
+			default:
+				return match, false
+			}
+		case 6:
+			switch {
+			case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z':
+				return match, false
+			default:
+				return i, false
+			}
+		}
+	}
+	if !eof {
+		return match, true
+	}
+	return match, false
+}
+
+// 'and'/!a
+func beeLexer20(src string, eof bool) (int, bool) {
+	match, state := -1, 0
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch state {
+		case 0:
+			switch {
+			case c == 'a':
+				state = 1
+			default:
+				return match, false
+			}
+		case 1:
+			switch {
+			case c == 'n':
+				state = 2
+			default:
+				return match, false
+			}
+		case 2:
+			switch {
+			case c == 'd':
+				state = 3
+			default:
+				return match, false
+			}
+		case 3:
+			switch {
+			case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z':
+				return match, false
+			default:
+				return i, false
+			}
+		}
+	}
+	if !eof {
+		return match, true
+	}
+	return match, false
+}
+
+// 'or'/!a
+func beeLexer21(src string, eof bool) (int, bool) {
+	match, state := -1, 0
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch state {
+		case 0:
+			switch {
+			case c == 'o':
+				state = 1
+			default:
+				return match, false
+			}
+		case 1:
+			switch {
+			case c == 'r':
+				state = 2
+			default:
+				return match, false
+			}
+		case 2:
+			switch {
+			case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z':
+				return match, false
+			default:
+				return i, false
+			}
+		}
+	}
+	if !eof {
+		return match, true
+	}
+	return match, false
+}
+
+// 'fn'/!a
+func beeLexer22(src string, eof bool) (int, bool) {
+	match, state := -1, 0
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch state {
+		case 0:
+			switch {
+			case c == 'f':
+				state = 1
+			default:
+				return match, false
+			}
+		case 1:
+			switch {
+			case c == 'n':
+				state = 2
+			default:
+				return match, false
+			}
+		case 2:
+			switch {
+			case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z':
+				return match, false
+			default:
+				return i, false
+			}
+		}
+	}
+	if !eof {
+		return match, true
+	}
+	return match, false
+}
+
+// '('
+func beeLexer23(src string, eof bool) (int, bool) {
+	match, state := -1, 0
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch state {
+		case 0:
+			switch {
+			case c == '(':
+				state = 1
+			default:
+				return match, false
+			}
+		case 1:
+			return i, false
+		}
+	}
+	if !eof {
+		return match, true
+	}
+	switch state {
+	case 1:
+		return len(src), false
+	}
+	return match, false
+}
+
+// ')'
+func beeLexer24(src string, eof bool) (int, bool) {
+	match, state := -1, 0
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch state {
+		case 0:
+			switch {
+			case c == ')':
+				state = 1
+			default:
+				return match, false
+			}
+		case 1:
+			return i, false
+		}
+	}
+	if !eof {
+		return match, true
+	}
+	switch state {
+	case 1:
+		return len(src), false
+	}
+	return match, false
+}
+
+// '{'
+func beeLexer25(src string, eof bool) (int, bool) {
+	match, state := -1, 0
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch state {
+		case 0:
+			switch {
+			case c == '{':
+				state = 1
+			default:
+				return match, false
+			}
+		case 1:
+			return i, false
+		}
+	}
+	if !eof {
+		return match, true
+	}
+	switch state {
+	case 1:
+		return len(src), false
+	}
+	return match, false
+}
+
+// '}'
+func beeLexer26(src string, eof bool) (int, bool) {
+	match, state := -1, 0
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch state {
+		case 0:
+			switch {
+			case c == '}':
+				state = 1
+			default:
+				return match, false
+			}
+		case 1:
+			return i, false
+		}
+	}
+	if !eof {
+		return match, true
+	}
+	switch state {
+	case 1:
+		return len(src), false
+	}
+	return match, false
+}
+
+// '['
+func beeLexer27(src string, eof bool) (int, bool) {
+	match, state := -1, 0
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch state {
+		case 0:
+			switch {
+			case c == '[':
+				state = 1
+			default:
+				return match, false
+			}
+		case 1:
+			return i, false
+		}
+	}
+	if !eof {
+		return match, true
+	}
+	switch state {
+	case 1:
+		return len(src), false
+	}
+	return match, false
+}
+
+// ']'
+func beeLexer28(src string, eof bool) (int, bool) {
+	match, state := -1, 0
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch state {
+		case 0:
+			switch {
+			case c == ']':
+				state = 1
+			default:
+				return match, false
+			}
+		case 1:
+			return i, false
+		}
+	}
+	if !eof {
+		return match, true
+	}
+	switch state {
+	case 1:
+		return len(src), false
+	}
+	return match, false
+}
+
+// '->'
+func beeLexer29(src string, eof bool) (int, bool) {
+	match, state := -1, 0
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch state {
+		case 0:
+			switch {
+			case c == '-':
+				state = 1
+			default:
+				return match, false
+			}
+		case 1:
+			switch {
+			case c == '>':
+				state = 2
+			default:
+				return match, false
+			}
+		case 2:
+			return i, false
+		}
+	}
+	if !eof {
+		return match, true
+	}
+	switch state {
+	case 2:
+		return len(src), false
+	}
+	return match, false
+}
+
+// '++'
+func beeLexer30(src string, eof bool) (int, bool) {
+	match, state := -1, 0
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch state {
+		case 0:
+			switch {
+			case c == '+':
+				state = 1
+			default:
+				return match, false
+			}
+		case 1:
+			switch {
+			case c == '+':
+				state = 2
+			default:
+				return match, false
+			}
+		case 2:
+			return i, false
+		}
+	}
+	if !eof {
+		return match, true
+	}
+	switch state {
+	case 2:
+		return len(src), false
+	}
+	return match, false
+}
+
+// '--'
+func beeLexer31(src string, eof bool) (int, bool) {
+	match, state := -1, 0
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch state {
+		case 0:
+			switch {
+			case c == '-':
+				state = 1
+			default:
+				return match, false
+			}
+		case 1:
+			switch {
+			case c == '-':
+				state = 2
+			default:
+				return match, false
+			}
+		case 2:
+			return i, false
+		}
+	}
+	if !eof {
+		return match, true
+	}
+	switch state {
+	case 2:
+		return len(src), false
+	}
+	return match, false
+}
+
+// '+'
+func beeLexer32(src string, eof bool) (int, bool) {
+	match, state := -1, 0
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch state {
+		case 0:
+			switch {
+			case c == '+':
+				state = 1
+			default:
+				return match, false
+			}
+		case 1:
+			return i, false
+		}
+	}
+	if !eof {
+		return match, true
+	}
+	switch state {
+	case 1:
+		return len(src), false
+	}
+	return match, false
+}
+
+// '-'
+func beeLexer33(src string, eof bool) (int, bool) {
+	match, state := -1, 0
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch state {
+		case 0:
+			switch {
+			case c == '-':
+				state = 1
+			default:
+				return match, false
+			}
+		case 1:
+			return i, false
+		}
+	}
+	if !eof {
+		return match, true
+	}
+	switch state {
+	case 1:
+		return len(src), false
+	}
+	return match, false
+}
+
+// '0b' [0-1]* [2-9] {<:idhead>|n}*
+func beeLexer34(src string, eof bool) (int, bool) {
+	match, state := -1, 0
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch state {
+		case 0:
+			switch {
+			case c == '0':
+				state = 1
+			default:
+				return match, false
+			}
+		case 1:
+			switch {
+			case c == 'b':
+				state = 2
+			default:
+				return match, false
+			}
+		case 2:
+			switch {
+			case '0' <= c && c <= '1':
+				state = 2
+			case '2' <= c && c <= '9':
+				state = 3
+			default:
+				return match, false
+			}
+		case 3:
+			switch {
+			case '0' <= c && c <= '9', 'A' <= c && c <= 'Z', c == '_', 'a' <= c && c <= 'z':
+				match, state = i, 3
+			default:
+				return i, false
+			}
+		}
+	}
+	if !eof {
+		return match, true
+	}
+	switch state {
+	case 3:
+		return len(src), false
+	}
+	return match, false
+}
+
+// '0b' [0-1]+
+func beeLexer35(src string, eof bool) (int, bool) {
+	match, state := -1, 0
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch state {
+		case 0:
+			switch {
+			case c == '0':
+				state = 1
+			default:
+				return match, false
+			}
+		case 1:
+			switch {
+			case c == 'b':
+				state = 2
+			default:
+				return match, false
+			}
+		case 2:
+			switch {
+			case '0' <= c && c <= '1':
+				state = 3
+			default:
+				return match, false
+			}
+		case 3:
+			switch {
+			case '0' <= c && c <= '1':
+				match, state = i, 3
+			default:
+				return i, false
+			}
+		}
+	}
+	if !eof {
+		return match, true
+	}
+	switch state {
+	case 3:
+		return len(src), false
+	}
+	return match, false
+}
+
+// '0x' <:hexdigit>+
+func beeLexer36(src string, eof bool) (int, bool) {
+	match, state := -1, 0
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch state {
+		case 0:
+			switch {
+			case c == '0':
+				state = 1
+			default:
+				return match, false
+			}
+		case 1:
+			switch {
+			case c == 'x':
+				state = 2
+			default:
+				return match, false
+			}
+		case 2:
+			switch {
+			case '0' <= c && c <= '9', 'A' <= c && c <= 'F', 'a' <= c && c <= 'f':
+				state = 3
+			default:
+				return match, false
+			}
+		case 3:
+			switch {
+			case '0' <= c && c <= '9', 'A' <= c && c <= 'F', 'a' <= c && c <= 'f':
+				match, state = i, 3
+			default:
+				return i, false
+			}
+		}
+	}
+	if !eof {
+		return match, true
+	}
+	switch state {
+	case 3:
+		return len(src), false
+	}
+	return match, false
+}
+
+// n+ <:idhead> {<:idhead>|n}*
+func beeLexer37(src string, eof bool) (int, bool) {
+	match, state := -1, 0
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch state {
+		case 0:
+			switch {
+			case '0' <= c && c <= '9':
+				state = 1
+			default:
+				return match, false
+			}
+		case 1:
+			switch {
+			case '0' <= c && c <= '9':
+				state = 1
+			case 'A' <= c && c <= 'Z', c == '_', 'a' <= c && c <= 'z':
+				state = 2
+			default:
+				return match, false
+			}
+		case 2:
+			switch {
+			case '0' <= c && c <= '9', 'A' <= c && c <= 'Z', c == '_', 'a' <= c && c <= 'z':
+				match, state = i, 2
+			default:
+				return i, false
+			}
+		}
+	}
+	if !eof {
+		return match, true
+	}
+	switch state {
+	case 2:
+		return len(src), false
+	}
+	return match, false
+}
+
+// {[0-9]+ '.' [0-9]*} | {[0-9]* '.' [0-9]+}
+func beeLexer38(src string, eof bool) (int, bool) {
+	match, state := -1, 0
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch state {
+		case 0:
+			switch {
+			case c == '.':
+				state = 1
+			case '0' <= c && c <= '9':
+				state = 2
+			default:
+				return match, false
+			}
+		case 1:
+			switch {
+			case '0' <= c && c <= '9':
+				state = 3
+			default:
+				return match, false
+			}
+		case 2:
+			switch {
+			case c == '.':
+				state = 4
+			case '0' <= c && c <= '9':
+				state = 2
+			default:
+				return match, false
+			}
+		case 3:
+			switch {
+			case '0' <= c && c <= '9':
+				match, state = i, 3
+			default:
+				return i, false
+			}
+		case 4:
+			switch {
+			case '0' <= c && c <= '9':
+				match, state = i, 5
+			default:
+				return i, false
+			}
+		case 5:
+			switch {
+			case '0' <= c && c <= '9':
+				match, state = i, 5
+			default:
+				return i, false
+			}
+		}
+	}
+	if !eof {
+		return match, true
+	}
+	switch state {
+	case 3, 4, 5:
+		return len(src), false
+	}
+	return match, false
+}
+
+// [0-9]+
+func beeLexer39(src string, eof bool) (int, bool) {
+	match, state := -1, 0
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch state {
+		case 0:
+			switch {
+			case '0' <= c && c <= '9':
+				state = 1
+			default:
+				return match, false
+			}
+		case 1:
+			switch {
+			case '0' <= c && c <= '9':
+				match, state = i, 1
+			default:
+				return i, false
+			}
+		}
+	}
+	if !eof {
+		return match, true
+	}
+	switch state {
+	case 1:
+		return len(src), false
+	}
+	return match, false
+}
+
+// Q {!Q}* Q
+func beeLexer40(src string, eof bool) (int, bool) {
+	match, state := -1, 0
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch state {
+		case 0:
+			switch {
+			case c == '"':
+				state = 1
+			default:
+				return match, false
+			}
+		case 1:
+			switch {
+			case c == '"':
+				state = 2
+			default:
+				state = 1
+			}
+		case 2:
+			return i, false
+		}
+	}
+	if !eof {
+		return match, true
+	}
+	switch state {
+	case 2:
+		return len(src), false
+	}
+	return match, false
+}
+
+// Q {!Q}*
+func beeLexer41(src string, eof bool) (int, bool) {
+	match, state := -1, 0
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch state {
+		case 0:
+			switch {
+			case c == '"':
+				state = 1
+			default:
+				return match, false
+			}
+		case 1:
+			switch {
+			case c == '"':
+				return i, false
+			default:
+				match, state = i, 1
+			}
+		}
+	}
+	if !eof {
+		return match, true
+	}
+	switch state {
+	case 1:
+		return len(src), false
+	}
+	return match, false
+}
+
+// q {<:escaped>|!{q|'\n'}}* q
+func beeLexer42(src string, eof bool) (int, bool) {
+	match, state := -1, 0
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch state {
+		case 0:
+			switch {
+			case c == '\'':
+				state = 1
+			default:
+				return match, false
+			}
+		case 1:
+			switch {
+			case c == '\n':
+				return match, false
+			case c == '\'':
+				state = 2
+			case c == '\\':
+				state = 3
+			default:
+				state = 1
+			}
+		case 2:
+			return i, false
+		case 3:
+			switch {
+			case c == '\'':
+				state = 4
+			case c == '\\':
+				state = 5
+			default:
+				state = 1
+			}
+		case 4:
+			switch {
+			case c == '\n':
+				return i, false
+			case c == '\'':
+				match, state = i, 2
+			case c == '\\':
+				match, state = i, 3
+			default:
+				match, state = i, 1
+			}
+		case 5:
+			switch {
+			case c == '\'':
+				state = 6
+			case c == '\\':
+				state = 3
+			default:
+				state = 1
+			}
+		case 6:
+			return i, false
+		}
+	}
+	if !eof {
+		return match, true
+	}
+	switch state {
+	case 2, 4, 6:
+		return len(src), false
+	}
+	return match, false
+}
+
+// q {<:escaped>|!{q|'\n'}}*
+func beeLexer43(src string, eof bool) (int, bool) {
+	match, state := -1, 0
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch state {
+		case 0:
+			switch {
+			case c == '\'':
+				state = 1
+			default:
+				return match, false
+			}
+		case 1:
+			switch {
+			case c == '\n', c == '\'':
+				return i, false
+			case c == '\\':
+				match, state = i, 2
+			default:
+				match, state = i, 1
+			}
+		case 2:
+			switch {
+			case c == '\\':
+				match, state = i, 3
+			default:
+				match, state = i, 1
+			}
+		case 3:
+			switch {
+			case c == '\n', c == '\'':
+				return i, false
+			case c == '\\':
+				match, state = i, 2
+			default:
+				match, state = i, 1
+			}
+		}
+	}
+	if !eof {
+		return match, true
+	}
+	switch state {
+	case 1, 2, 3:
+		return len(src), false
+	}
+	return match, false
+}
+
+// '\x60' {<:escaped>|!{'\x60'|'\n'}}* '\x60'
+func beeLexer44(src string, eof bool) (int, bool) {
+	match, state := -1, 0
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch state {
+		case 0:
+			switch {
+			case c == '`':
+				state = 1
+			default:
+				return match, false
+			}
+		case 1:
+			switch {
+			case c == '\n':
+				return match, false
+			case c == '\\':
+				state = 2
+			case c == '`':
+				state = 3
+			default:
+				state = 1
+			}
+		case 2:
+			switch {
+			case c == '\\':
+				state = 4
+			case c == '`':
+				state = 5
+			default:
+				state = 1
+			}
+		case 3:
+			return i, false
+		case 4:
+			switch {
+			case c == '\\':
+				state = 2
+			case c == '`':
+				state = 6
+			default:
+				state = 1
+			}
+		case 5:
+			switch {
+			case c == '\n':
+				return i, false
+			case c == '\\':
+				match, state = i, 2
+			case c == '`':
+				match, state = i, 3
+			default:
+				match, state = i, 1
+			}
+		case 6:
+			return i, false
+		}
+	}
+	if !eof {
+		return match, true
+	}
+	switch state {
+	case 3, 5, 6:
+		return len(src), false
+	}
+	return match, false
+}
+
+// '\x60' {<:escaped>|!{'\x60'|'\n'}}*
+func beeLexer45(src string, eof bool) (int, bool) {
+	match, state := -1, 0
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch state {
+		case 0:
+			switch {
+			case c == '`':
+				state = 1
+			default:
+				return match, false
+			}
+		case 1:
+			switch {
+			case c == '\n', c == '`':
+				return i, false
+			case c == '\\':
+				match, state = i, 2
+			default:
+				match, state = i, 1
+			}
+		case 2:
+			switch {
+			case c == '\\':
+				match, state = i, 3
+			default:
+				match, state = i, 1
+			}
+		case 3:
+			switch {
+			case c == '\n', c == '`':
+				return i, false
+			case c == '\\':
+				match, state = i, 2
+			default:
+				match, state = i, 1
+			}
+		}
+	}
+	if !eof {
+		return match, true
+	}
+	switch state {
+	case 1, 2, 3:
+		return len(src), false
+	}
+	return match, false
+}
+
+// <:idhead> {<:idhead>|n}*
+func beeLexer46(src string, eof bool) (int, bool) {
+	match, state := -1, 0
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch state {
+		case 0:
+			switch {
+			case 'A' <= c && c <= 'Z', c == '_', 'a' <= c && c <= 'z':
+				state = 1
+			default:
+				return match, false
+			}
+		case 1:
+			switch {
+			case '0' <= c && c <= '9', 'A' <= c && c <= 'Z', c == '_', 'a' <= c && c <= 'z':
+				match, state = i, 1
+			default:
+				return i, false
+			}
+		}
+	}
+	if !eof {
+		return match, true
+	}
+	switch state {
+	case 1:
+		return len(src), false
+	}
+	return match, false
+}
+
+// '::'
+func beeLexer47(src string, eof bool) (int, bool) {
+	match, state := -1, 0
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch state {
+		case 0:
+			switch {
+			case c == ':':
+				state = 1
+			default:
+				return match, false
+			}
+		case 1:
+			switch {
+			case c == ':':
+				state = 2
+			default:
+				return match, false
+			}
+		case 2:
+			return i, false
+		}
+	}
+	if !eof {
+		return match, true
+	}
+	switch state {
+	case 2:
+		return len(src), false
+	}
+	return match, false
+}
+
+// ':'
+func beeLexer48(src string, eof bool) (int, bool) {
+	match, state := -1, 0
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch state {
+		case 0:
+			switch {
+			case c == ':':
+				state = 1
+			default:
+				return match, false
+			}
+		case 1:
+			return i, false
+		}
+	}
+	if !eof {
+		return match, true
+	}
+	switch state {
+	case 1:
+		return len(src), false
+	}
+	return match, false
+}
+
+// '~'
+func beeLexer49(src string, eof bool) (int, bool) {
+	match, state := -1, 0
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch state {
+		case 0:
+			switch {
+			case c == '~':
+				state = 1
+			default:
+				return match, false
+			}
+		case 1:
+			return i, false
+		}
+	}
+	if !eof {
+		return match, true
+	}
+	switch state {
+	case 1:
+		return len(src), false
+	}
+	return match, false
+}
+
+// '|'
+func beeLexer50(src string, eof bool) (int, bool) {
+	match, state := -1, 0
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch state {
+		case 0:
+			switch {
+			case c == '|':
+				state = 1
+			default:
+				return match, false
+			}
+		case 1:
+			return i, false
+		}
+	}
+	if !eof {
+		return match, true
+	}
+	switch state {
+	case 1:
+		return len(src), false
+	}
+	return match, false
+}
+
+// '^'
+func beeLexer51(src string, eof bool) (int, bool) {
+	match, state := -1, 0
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch state {
+		case 0:
+			switch {
+			case c == '^':
+				state = 1
+			default:
+				return match, false
+			}
+		case 1:
+			return i, false
+		}
+	}
+	if !eof {
+		return match, true
+	}
+	switch state {
+	case 1:
+		return len(src), false
+	}
+	return match, false
+}
+
+// '<<'
+func beeLexer52(src string, eof bool) (int, bool) {
+	match, state := -1, 0
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch state {
+		case 0:
+			switch {
+			case c == '<':
+				state = 1
+			default:
+				return match, false
+			}
+		case 1:
+			switch {
+			case c == '<':
+				state = 2
+			default:
+				return match, false
+			}
+		case 2:
+			return i, false
+		}
+	}
+	if !eof {
+		return match, true
+	}
+	switch state {
+	case 2:
+		return len(src), false
+	}
+	return match, false
+}
+
+// '>>'
+func beeLexer53(src string, eof bool) (int, bool) {
+	match, state := -1, 0
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch state {
+		case 0:
+			switch {
+			case c == '>':
+				state = 1
+			default:
+				return match, false
+			}
+		case 1:
+			switch {
+			case c == '>':
+				state = 2
+			default:
+				return match, false
+			}
+		case 2:
+			return i, false
+		}
+	}
+	if !eof {
+		return match, true
+	}
+	switch state {
+	case 2:
+		return len(src), false
+	}
+	return match, false
+}
+
+// '/'
+func beeLexer54(src string, eof bool) (int, bool) {
+	match, state := -1, 0
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch state {
+		case 0:
+			switch {
+			case c == '/':
+				state = 1
+			default:
+				return match, false
+			}
+		case 1:
+			return i, false
+		}
+	}
+	if !eof {
+		return match, true
+	}
+	switch state {
+	case 1:
+		return len(src), false
+	}
+	return match, false
+}
+
+// '%'
+func beeLexer55(src string, eof bool) (int, bool) {
+	match, state := -1, 0
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch state {
+		case 0:
+			switch {
+			case c == '%':
+				state = 1
+			default:
+				return match, false
+			}
+		case 1:
+			return i, false
+		}
+	}
+	if !eof {
+		return match, true
+	}
+	switch state {
+	case 1:
+		return len(src), false
+	}
+	return match, false
+}
+
+// '=='
+func beeLexer56(src string, eof bool) (int, bool) {
+	match, state := -1, 0
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch state {
+		case 0:
+			switch {
+			case c == '=':
+				state = 1
+			default:
+				return match, false
+			}
+		case 1:
+			switch {
+			case c == '=':
+				state = 2
+			default:
+				return match, false
+			}
+		case 2:
+			return i, false
+		}
+	}
+	if !eof {
+		return match, true
+	}
+	switch state {
+	case 2:
+		return len(src), false
+	}
+	return match, false
+}
+
+// '!='
+func beeLexer57(src string, eof bool) (int, bool) {
+	match, state := -1, 0
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch state {
+		case 0:
+			switch {
+			case c == '!':
+				state = 1
+			default:
+				return match, false
+			}
+		case 1:
+			switch {
+			case c == '=':
+				state = 2
+			default:
+				return match, false
+			}
+		case 2:
+			return i, false
+		}
+	}
+	if !eof {
+		return match, true
+	}
+	switch state {
+	case 2:
+		return len(src), false
+	}
+	return match, false
+}
+
+// '<='
+func beeLexer58(src string, eof bool) (int, bool) {
+	match, state := -1, 0
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch state {
+		case 0:
+			switch {
+			case c == '<':
+				state = 1
+			default:
+				return match, false
+			}
+		case 1:
+			switch {
+			case c == '=':
+				state = 2
+			default:
+				return match, false
+			}
+		case 2:
+			return i, false
+		}
+	}
+	if !eof {
+		return match, true
+	}
+	switch state {
+	case 2:
+		return len(src), false
+	}
+	return match, false
+}
+
+// '>='
+func beeLexer59(src string, eof bool) (int, bool) {
+	match, state := -1, 0
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch state {
+		case 0:
+			switch {
+			case c == '>':
+				state = 1
+			default:
+				return match, false
+			}
+		case 1:
+			switch {
+			case c == '=':
+				state = 2
+			default:
+				return match, false
+			}
+		case 2:
+			return i, false
+		}
+	}
+	if !eof {
+		return match, true
+	}
+	switch state {
+	case 2:
+		return len(src), false
+	}
+	return match, false
+}
+
+// '<'
+func beeLexer60(src string, eof bool) (int, bool) {
+	match, state := -1, 0
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch state {
+		case 0:
+			switch {
+			case c == '<':
+				state = 1
+			default:
+				return match, false
+			}
+		case 1:
+			return i, false
+		}
+	}
+	if !eof {
+		return match, true
+	}
+	switch state {
+	case 1:
+		return len(src), false
+	}
+	return match, false
+}
+
+// '>'
+func beeLexer61(src string, eof bool) (int, bool) {
+	match, state := -1, 0
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch state {
+		case 0:
+			switch {
+			case c == '>':
+				state = 1
+			default:
+				return match, false
+			}
+		case 1:
+			return i, false
+		}
+	}
+	if !eof {
+		return match, true
+	}
+	switch state {
+	case 1:
+		return len(src), false
+	}
+	return match, false
+}
+
+// '!'
+func beeLexer62(src string, eof bool) (int, bool) {
+	match, state := -1, 0
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch state {
+		case 0:
+			switch {
+			case c == '!':
+				state = 1
+			default:
+				return match, false
+			}
+		case 1:
+			return i, false
+		}
+	}
+	if !eof {
+		return match, true
+	}
+	switch state {
+	case 1:
+		return len(src), false
+	}
+	return match, false
+}
+
+// '='
+func beeLexer63(src string, eof bool) (int, bool) {
+	match, state := -1, 0
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch state {
+		case 0:
+			switch {
+			case c == '=':
+				state = 1
+			default:
+				return match, false
+			}
+		case 1:
+			return i, false
+		}
+	}
+	if !eof {
+		return match, true
+	}
+	switch state {
+	case 1:
+		return len(src), false
+	}
+	return match, false
+}
+
+// '&'
+func beeLexer64(src string, eof bool) (int, bool) {
+	match, state := -1, 0
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch state {
+		case 0:
+			switch {
+			case c == '&':
+				state = 1
+			default:
+				return match, false
+			}
+		case 1:
+			return i, false
+		}
+	}
+	if !eof {
+		return match, true
+	}
+	switch state {
+	case 1:
+		return len(src), false
+	}
+	return match, false
+}
+
+// '*'
+func beeLexer65(src string, eof bool) (int, bool) {
+	match, state := -1, 0
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch state {
+		case 0:
+			switch {
+			case c == '*':
+				state = 1
+			default:
+				return match, false
+			}
+		case 1:
+			return i, false
+		}
+	}
+	if !eof {
+		return match, true
+	}
+	switch state {
+	case 1:
+		return len(src), false
+	}
+	return match, false
+}
+
+// '.'
+func beeLexer66(src string, eof bool) (int, bool) {
+	match, state := -1, 0
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch state {
+		case 0:
+			switch {
+			case c == '.':
+				state = 1
+			default:
+				return match, false
+			}
+		case 1:
+			return i, false
+		}
+	}
+	if !eof {
+		return match, true
+	}
+	switch state {
+	case 1:
+		return len(src), false
+	}
+	return match, false
+}
+
+// ','
+func beeLexer67(src string, eof bool) (int, bool) {
+	match, state := -1, 0
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch state {
+		case 0:
+			switch {
+			case c == ',':
+				state = 1
+			default:
+				return match, false
+			}
+		case 1:
+			return i, false
+		}
+	}
+	if !eof {
+		return match, true
+	}
+	switch state {
+	case 1:
+		return len(src), false
+	}
+	return match, false
+}
+
+// ';'
+func beeLexer68(src string, eof bool) (int, bool) {
+	match, state := -1, 0
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch state {
+		case 0:
+			switch {
+			case c == ';':
+				state = 1
+			default:
+				return match, false
+			}
+		case 1:
+			return i, false
+		}
+	}
+	if !eof {
+		return match, true
+	}
+	switch state {
+	case 1:
+		return len(src), false
+	}
+	return match, false
+}
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Exit codes of the subcommands
+const (
+	exitOk      = 0
+	exitFailure = 1
+	exitUsage   = 2
+)
+
+const regexUsage = `Usage:
+	bee regex graph PATTERN        DOT graph of the pattern
+	bee regex match PATTERN INPUT  Prefix of the input matched by the pattern
+	bee regex test FILE            Runs the table of tests of the file`
+
+// bee regex subcommand, the exit code fails on invalid patterns, inputs without a match
+// and failed tests
+func regexCommand(args []string, stdout io.Writer, stderr io.Writer) int {
+	usage := func() int {
+		fmt.Fprintln(stderr, regexUsage)
+		return exitUsage
+	}
+	if len(args) < 1 {
+		return usage()
+	}
+
+	switch cmd, args := args[0], args[1:]; {
+	case cmd == "graph" && len(args) == 1:
+		rx, err := NewRegex(args[0])
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return exitFailure
+		}
+		fmt.Fprint(stdout, rx.Graph(args[0]))
+		return exitOk
+
+	case cmd == "match" && len(args) == 2:
+		rx, err := NewRegex(args[0])
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return exitFailure
+		}
+		match := rx.Match(args[1])
+		if match < 0 {
+			fmt.Fprintf(stderr, "%s does not match %s\n", strconv.Quote(args[0]), strconv.Quote(args[1]))
+			return exitFailure
+		}
+		fmt.Fprintln(stdout, args[1][:match])
+		return exitOk
+
+	case cmd == "test" && len(args) == 1:
+		file, err := os.Open(args[0])
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return exitFailure
+		}
+		defer file.Close()
+		return regexTest(args[0], file, stdout, stderr)
+	}
+
+	return usage()
+}
+
+// Runs a table of tests, one per line as PATTERN <tab> INPUT <tab> EXPECTED where the
+// input and the expected prefix are Go quoted strings, the expected prefix is none
+// without a match. Blank lines and lines starting with // are skipped
+//
+//	Example: 'a'+ <tab> "aab" <tab> "aa"
+func regexTest(name string, r io.Reader, stdout io.Writer, stderr io.Writer) int {
+	passed, failed := 0, 0
+	fail := func(line int, f string, args ...interface{}) {
+		fmt.Fprintf(stdout, "%s:%d: %s\n", name, line, fmt.Sprintf(f, args...))
+		failed++
+	}
+
+	sc := bufio.NewScanner(r)
+	for line := 1; sc.Scan(); line++ {
+		text := sc.Text()
+		if strings.TrimSpace(text) == "" || strings.HasPrefix(strings.TrimSpace(text), "//") {
+			continue
+		}
+
+		fields := strings.Split(text, "\t")
+		if len(fields) != 3 {
+			fail(line, "%d fields instead of PATTERN <tab> INPUT <tab> EXPECTED", len(fields))
+			continue
+		}
+		input, err := strconv.Unquote(fields[1])
+		if err != nil {
+			fail(line, "Input %s is not a quoted string", fields[1])
+			continue
+		}
+		want, expected := -1, ""
+		if fields[2] != "none" {
+			if expected, err = strconv.Unquote(fields[2]); err != nil {
+				fail(line, "Expected %s is neither a quoted string nor none", fields[2])
+				continue
+			}
+			want = len(expected)
+		}
+
+		rx, err := NewRegex(fields[0])
+		if err != nil {
+			fail(line, "%s", err)
+			continue
+		}
+		match := rx.Match(input)
+		switch {
+		case match == want && (want < 0 || input[:match] == expected):
+			passed++
+		case match < 0:
+			fail(line, "%s does not match %s, expected %s", strconv.Quote(fields[0]), fields[1], fields[2])
+		default:
+			fail(line, "%s matched %s of %s, expected %s", strconv.Quote(fields[0]), strconv.Quote(input[:match]), fields[1], fields[2])
+		}
+	}
+	if err := sc.Err(); err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitFailure
+	}
+
+	fmt.Fprintf(stdout, "%d passed, %d failed\n", passed, failed)
+	if failed != 0 {
+		return exitFailure
+	}
+	return exitOk
+}
+
+const tokensUsage = `Usage:
+	bee tokens [-json] [-trivia] [-grammar GRAMMAR] FILE  Tokens of the file as a table or as JSON lines`
+
+// Token of the bee tokens subcommand, trivia tells whether a comment leads or trails the
+// token next to it
+type tokenRow struct {
+	Expr   string `json:"expr"`
+	Trait  string `json:"trait"`
+	Line   int    `json:"line"`
+	Col    int    `json:"col"`
+	Offset int    `json:"offset"`
+	End    int    `json:"end"`
+	Trivia string `json:"trivia,omitempty"`
+}
+
+func newTokenRow(tok Token, trivia string) tokenRow {
+	return tokenRow{tok.Expr, tok.Trait.Repr(), tok.Pos.Line, tok.Pos.Col, tok.Pos.Offset, tok.Pos.End, trivia}
+}
+
+// bee tokens subcommand, scans the file with the generated lexer of NewBeeSyntax or with
+// the syntax map of -grammar, see LoadSyntaxMap. The comments are only listed with -trivia
+func tokensCommand(args []string, stdout io.Writer, stderr io.Writer) int {
+	flags := flag.NewFlagSet("tokens", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, tokensUsage)
+	}
+	asJson := flags.Bool("json", false, "")
+	trivia := flags.Bool("trivia", false, "")
+	grammar := flags.String("grammar", "", "")
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return exitUsage
+	}
+
+	var sm SyntaxMap
+	if *grammar != "" {
+		src, err := os.Open(*grammar)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return exitFailure
+		}
+		sm, err = LoadSyntaxMap(*grammar, src)
+		src.Close()
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return exitFailure
+		}
+	}
+
+	file, err := os.Open(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitFailure
+	}
+	defer file.Close()
+
+	rows := make([]tokenRow, 0, 256)
+	enc := json.NewEncoder(stdout)
+	enc.SetEscapeHTML(false)
+	emit := func(tok Token, kind string) {
+		if *asJson {
+			enc.Encode(newTokenRow(tok, kind))
+		} else {
+			rows = append(rows, newTokenRow(tok, kind))
+		}
+	}
+
+	sn := NewLexerReaderScanner(file, BeeLexer)
+	if sm != nil {
+		sn = NewReaderScanner(file, sm)
+	}
+	for {
+		tok := sn.Tokenize()
+		for i := 0; *trivia && i < len(tok.Leading); i++ {
+			emit(tok.Leading[i], "leading")
+		}
+		if tok.Trait == Eof {
+			break
+		}
+		emit(tok, "")
+		for i := 0; *trivia && i < len(tok.Trailing); i++ {
+			emit(tok.Trailing[i], "trailing")
+		}
+	}
+	if err := sn.Err(); err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitFailure
+	}
+
+	writeTokenTable(stdout, rows)
+	return exitOk
+}
+
+// Columns of the expressions, trait names, line:col positions and trivia aligned
+func writeTokenTable(w io.Writer, rows []tokenRow) {
+	exprs := make([]string, len(rows))
+	widths := [3]int{}
+	for i, row := range rows {
+		exprs[i] = strconv.Quote(row.Expr)
+		pos := fmt.Sprintf("%d:%d", row.Line, row.Col)
+		for col, cell := range [3]string{exprs[i], row.Trait, pos} {
+			if n := utf8.RuneCountInString(cell); n > widths[col] {
+				widths[col] = n
+			}
+		}
+	}
+	for i, row := range rows {
+		pos := fmt.Sprintf("%d:%d", row.Line, row.Col)
+		line := fmt.Sprintf("%-*s  %-*s  %-*s  %s", widths[0], exprs[i], widths[1], row.Trait, widths[2], pos, row.Trivia)
+		fmt.Fprintln(w, strings.TrimRight(line, " "))
+	}
+}
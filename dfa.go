@@ -0,0 +1,443 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Subset construction over the regex node graph. The graph is first lowered into
+// a byte-level program, then determinized with priority-ordered thread lists so the
+// DFA reports the same match as the backtracking node.Submit (first path wins).
+
+const dfaMaxStates = 4096
+
+type byteSet [4]uint64
+
+type instOp uint
+
+const (
+	instSplit instOp = iota
+	instByte
+	instPeek
+	instMatch
+)
+
+type inst struct {
+	op   instOp
+	set  byteSet
+	outs []int
+}
+
+type prog struct {
+	insts []inst
+	start int
+}
+
+type progBuilder struct {
+	prog    *prog
+	entries map[*node]int
+	match   int
+}
+
+type dfaState struct {
+	next   []int
+	accept []bool
+	eof    bool
+}
+
+type dfa struct {
+	classes [256]byte
+	states  []dfaState
+}
+
+func (bs *byteSet) Add(c byte) {
+	bs[c/64] |= 1 << (c % 64)
+}
+
+func (bs *byteSet) AddRange(a, b byte) {
+	for c := int(a); c <= int(b); c++ {
+		bs.Add(byte(c))
+	}
+}
+
+func (bs *byteSet) Union(other byteSet) {
+	for i := range bs {
+		bs[i] |= other[i]
+	}
+}
+
+func (bs *byteSet) Invert() {
+	for i := range bs {
+		bs[i] = ^bs[i]
+	}
+}
+
+func (bs *byteSet) Has(c byte) bool {
+	return bs[c/64]&(1<<(c%64)) != 0
+}
+
+// Adds the other case of the ASCII letters in the set
+func (bs *byteSet) Fold() {
+	for c := byte('A'); c <= 'Z'; c++ {
+		if lower := c + 'a' - 'A'; bs.Has(c) || bs.Has(lower) {
+			bs.Add(c)
+			bs.Add(lower)
+		}
+	}
+}
+
+// Set of bytes matched by a state consuming exactly one byte. States matching runes
+// only qualify when restricted to ASCII, where a rune is a single byte. Non-ASCII runes
+// may fold to ASCII letters, folded states matching runes do not qualify
+func (s *state) byteSet() (byteSet, bool) {
+	var bs byteSet
+	if s.runes && s.flags&flagFold != 0 {
+		return bs, false
+	}
+
+	switch s.Tag {
+	case none:
+		return bs, true
+
+	case anything:
+		if s.runes {
+			return bs, false
+		}
+		bs.Invert()
+		if s.flags&flagNoNewline != 0 {
+			bs[0] &^= 1 << '\n'
+		}
+		return bs, true
+
+	case text:
+		if len(s.str) != 1 {
+			return bs, false
+		}
+		bs.Add(s.str[0])
+		s.fold(&bs)
+		return bs, true
+
+	case set:
+		for i := 0; i < len(s.str); i++ {
+			if s.runes && s.str[i] >= utf8.RuneSelf {
+				return bs, false
+			}
+			bs.Add(s.str[i])
+		}
+		s.fold(&bs)
+		return bs, true
+
+	case scope:
+		if s.runes && s.neg {
+			return bs, false
+		}
+		for _, r := range s.ranges {
+			if r[1] > 0xff || (s.runes && r[1] >= utf8.RuneSelf) {
+				return bs, false
+			}
+			bs.AddRange(byte(r[0]), byte(r[1]))
+		}
+		s.fold(&bs)
+		if s.neg {
+			bs.Invert()
+		}
+		return bs, true
+
+	case not:
+		if s.runes {
+			return bs, false
+		}
+		if bs, ok := s.seq.byteSet(); ok {
+			bs.Invert()
+			return bs, true
+		}
+	}
+
+	return bs, false
+}
+
+func (s *state) fold(bs *byteSet) {
+	if s.flags&flagFold != 0 {
+		bs.Fold()
+	}
+}
+
+// Set of bytes matched by a sequence whose language only contains single bytes,
+// such as {q|'\n'} used in lookaheads and negations
+func (n *node) byteSet() (byteSet, bool) {
+	if n.state.Tag != epsilon {
+		if len(n.edges) != 0 {
+			return byteSet{}, false
+		}
+		return n.state.byteSet()
+	}
+
+	var bs byteSet
+	if len(n.edges) == 0 {
+		return bs, false
+	}
+	for _, edge := range n.edges {
+		if edge.index <= n.index {
+			return bs, false
+		}
+		other, ok := edge.byteSet()
+		if !ok {
+			return bs, false
+		}
+		bs.Union(other)
+	}
+	return bs, true
+}
+
+func compileProg(head *node) (*prog, bool) {
+	b := progBuilder{prog: &prog{}, entries: make(map[*node]int)}
+	b.match = b.emit(inst{op: instMatch})
+
+	start, ok := b.enter(head)
+	if !ok {
+		return nil, false
+	}
+	b.prog.start = start
+	return b.prog, true
+}
+
+func (b *progBuilder) emit(in inst) int {
+	b.prog.insts = append(b.prog.insts, in)
+	return len(b.prog.insts) - 1
+}
+
+// Lowers the node into instructions, returns the entry pc consuming its state
+func (b *progBuilder) enter(n *node) (int, bool) {
+	if pc, found := b.entries[n]; found {
+		return pc, true
+	}
+
+	exit := b.emit(inst{op: instSplit})
+	entry := exit
+	s := &n.state
+
+	switch s.Tag {
+	case epsilon, begin, end:
+
+	case text:
+		if len(s.str) == 0 || (s.runes && s.flags&flagFold != 0) {
+			return 0, false
+		}
+		for i := len(s.str) - 1; i >= 0; i-- {
+			var bs byteSet
+			bs.Add(s.str[i])
+			s.fold(&bs)
+			entry = b.emit(inst{op: instByte, set: bs, outs: []int{entry}})
+		}
+
+	case dash:
+		bs, ok := s.seq.byteSet()
+		if !ok {
+			return 0, false
+		}
+		entry = b.emit(inst{op: instPeek, set: bs, outs: []int{exit}})
+
+	default:
+		bs, ok := s.byteSet()
+		if !ok {
+			return 0, false
+		}
+		entry = b.emit(inst{op: instByte, set: bs, outs: []int{exit}})
+	}
+
+	b.entries[n] = entry
+	outs := make([]int, 0, len(n.edges)+1)
+	for _, edge := range n.edges {
+		pc, ok := b.enter(edge)
+		if !ok {
+			return 0, false
+		}
+		outs = append(outs, pc)
+	}
+	if !n.Branch() {
+		outs = append(outs, b.match)
+	}
+	b.prog.insts[exit].outs = outs
+
+	return entry, true
+}
+
+// Follows the epsilon splits from pc, appending the reached threads by priority
+func (pg *prog) addThread(list []int, seen []bool, pc int) []int {
+	if seen[pc] {
+		return list
+	}
+	seen[pc] = true
+
+	if in := &pg.insts[pc]; in.op == instSplit {
+		for _, out := range in.outs {
+			list = pg.addThread(list, seen, out)
+		}
+		return list
+	}
+	return append(list, pc)
+}
+
+// Advances the thread list over c (-1 at the end of input). The accept result tells
+// whether a thread matched before consuming c, lower priority threads are then cut
+// unless looking for the longest match
+func (pg *prog) step(list []int, c int, longest bool) ([]int, bool) {
+	accept := false
+	next := make([]int, 0, len(list))
+	nextSeen := make([]bool, len(pg.insts))
+	curSeen := make([]bool, len(pg.insts))
+
+	var walk func(list []int) bool
+	walk = func(list []int) bool {
+		for _, pc := range list {
+			if curSeen[pc] {
+				continue
+			}
+			curSeen[pc] = true
+
+			in := &pg.insts[pc]
+			switch in.op {
+			case instMatch:
+				accept = true
+				if !longest {
+					return true
+				}
+
+			case instByte:
+				if c >= 0 && in.set.Has(byte(c)) {
+					next = pg.addThread(next, nextSeen, in.outs[0])
+				}
+
+			case instPeek:
+				if c >= 0 && in.set.Has(byte(c)) {
+					peek := pg.addThread(nil, make([]bool, len(pg.insts)), in.outs[0])
+					if walk(peek) {
+						return true
+					}
+				}
+			}
+		}
+		return false
+	}
+
+	walk(list)
+	return next, accept
+}
+
+func (pg *prog) byteClasses() ([256]byte, []byte) {
+	var classes [256]byte
+	reprs := make([]byte, 0, 16)
+	keys := make(map[string]byte)
+
+	for c := 0; c < 256; c++ {
+		var key strings.Builder
+		for i := range pg.insts {
+			in := &pg.insts[i]
+			if in.op == instByte || in.op == instPeek {
+				if in.set.Has(byte(c)) {
+					key.WriteByte('1')
+				} else {
+					key.WriteByte('0')
+				}
+			}
+		}
+
+		class, found := keys[key.String()]
+		if !found {
+			class = byte(len(reprs))
+			keys[key.String()] = class
+			reprs = append(reprs, byte(c))
+		}
+		classes[c] = class
+	}
+
+	return classes, reprs
+}
+
+func threadKey(list []int) string {
+	var sb strings.Builder
+	for _, pc := range list {
+		sb.WriteString(strconv.Itoa(pc))
+		sb.WriteByte(',')
+	}
+	return sb.String()
+}
+
+func newDfa(pg *prog, longest bool) (*dfa, bool) {
+	d := &dfa{}
+	classes, reprs := pg.byteClasses()
+	d.classes = classes
+
+	ids := make(map[string]int)
+	lists := make([][]int, 0, 16)
+
+	state := func(list []int) int {
+		if len(list) == 0 {
+			return -1
+		}
+		key := threadKey(list)
+		if id, found := ids[key]; found {
+			return id
+		}
+		ids[key] = len(lists)
+		lists = append(lists, list)
+		return len(lists) - 1
+	}
+
+	start := pg.addThread(nil, make([]bool, len(pg.insts)), pg.start)
+	if len(start) == 0 {
+		return nil, false
+	}
+	state(start)
+
+	for id := 0; id < len(lists); id++ {
+		if len(lists) > dfaMaxStates {
+			return nil, false
+		}
+
+		s := dfaState{
+			next:   make([]int, len(reprs)),
+			accept: make([]bool, len(reprs)),
+		}
+		for class, c := range reprs {
+			next, accept := pg.step(lists[id], int(c), longest)
+			s.next[class] = state(next)
+			s.accept[class] = accept
+		}
+		_, s.eof = pg.step(lists[id], -1, longest)
+		d.states = append(d.states, s)
+	}
+
+	return d, true
+}
+
+func (d *dfa) Match(expr string) int {
+	match, _ := d.MatchMore(expr, true)
+	return match
+}
+
+// Without eof, a state still alive at the end of expr needs more input to decide
+func (d *dfa) MatchMore(expr string, eof bool) (int, bool) {
+	match := -1
+	id := 0
+
+	for i := 0; i < len(expr); i++ {
+		s := &d.states[id]
+		class := d.classes[expr[i]]
+		if s.accept[class] {
+			match = i
+		}
+		if id = s.next[class]; id < 0 {
+			return match, false
+		}
+	}
+
+	if !eof {
+		return match, true
+	}
+	if d.states[id].eof {
+		match = len(expr)
+	}
+	return match, false
+}
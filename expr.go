@@ -4,25 +4,30 @@ type UnaryExpr struct {
 	Order    Order
 	Operand  Node
 	Operator Token
+	Span
 }
 
 type BinaryExpr struct {
 	Operands [2]Node
 	Operator Token
+	Span
 }
 
 type IndexExpr struct {
 	Operand Node
+	Span
 }
 
 type InvokeExpr struct {
 	Operand Fn
 	Args    []Var
+	Span
 }
 
 type DefineExpr struct {
 	Def  Def
 	Expr Node
+	Span
 }
 type DeclareExpr DefineExpr
 
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Named regex sources referenced as <:name> from other patterns, such as <:hexdigit>+.
+// A fragment is parsed once for each mode and set of modifiers, its graph is then
+// copied in place of the references
+type Fragments struct {
+	srcs   map[string]string
+	graphs map[fragmentKey]*node
+	// Fragments being parsed, a reference to one of them is a cycle
+	stack []string
+}
+
+type fragmentKey struct {
+	name  string
+	mode  RegexMode
+	flags matchFlag
+}
+
+func NewFragments() *Fragments {
+	return &Fragments{
+		srcs:   make(map[string]string),
+		graphs: make(map[fragmentKey]*node),
+		stack:  make([]string, 0, 4),
+	}
+}
+
+// Registers the source of a fragment, references are resolved when compiling the
+// patterns so fragments may be defined in any order
+func (f *Fragments) Define(name string, src string) error {
+	p := newRegexParser(":" + name + ">")
+	if p.readFragmentName() != name {
+		return fmt.Errorf("'%s': Fragment name is not made of letters, digits and <_>", name)
+	}
+	if _, found := f.srcs[name]; found {
+		return fmt.Errorf("'%s': Fragment is already defined", name)
+	}
+	f.srcs[name] = src
+	return nil
+}
+
+// Copy of the fragment graph parsed in the context of the referencing parser
+func (f *Fragments) graph(p *parser, at int, name string) (*node, error) {
+	src, found := f.srcs[name]
+	if !found {
+		return nil, p.errorAt(at, '<', "'%s': Unknown fragment", name)
+	}
+
+	key := fragmentKey{name, p.mode, p.flags}
+	if head, found := f.graphs[key]; found {
+		return head.Clone(), nil
+	}
+
+	for i, parsing := range f.stack {
+		if parsing == name {
+			cycle := append(f.stack[i:len(f.stack):len(f.stack)], name)
+			return nil, p.errorAt(at, '<', "Cyclic fragment reference <:%s>", strings.Join(cycle, "> -> <:"))
+		}
+	}
+
+	fp := newRegexParser(src)
+	fp.mode, fp.flags = p.mode, p.flags
+	fp.fragments, fp.fragment = f, name
+
+	f.stack = append(f.stack, name)
+	head, err := fp.Parse()
+	f.stack = f.stack[:len(f.stack)-1]
+
+	if err != nil {
+		return nil, err
+	}
+	if head == nil {
+		return nil, p.errorAt(at, '<', "'%s': Fragment is empty", name)
+	}
+	f.graphs[key] = head
+	return head.Clone(), nil
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"fmt"
+	"go/format"
+	"io"
+	"strings"
+)
+
+// Go source of a lexer with the same output as the SyntaxMap, each pattern becomes a
+// function walking the states of its DFA in a switch, or counting the depth of its Nest.
+// Patterns that are not determinized, with anchors or lookbehinds, cannot be generated
+type lexerGen struct {
+	sb   strings.Builder
+	name string
+}
+
+// Outcome of a byte in a DFA state: the state matched before the byte, then the next
+// state or -1 to stop
+type lexerEdge struct {
+	accept bool
+	next   int
+}
+
+// Writes a Go file of the package declaring name as a Lexer for the syntax map
+func (sm SyntaxMap) Generate(w io.Writer, pkg string, name string) error {
+	g := lexerGen{name: name}
+	prefix := strings.ToLower(name[:1]) + name[1:]
+
+	g.writeln("// Code generated by SyntaxMap.Generate. DO NOT EDIT.")
+	g.writeln("")
+	g.writeln("package %s", pkg)
+	g.writeln("")
+	g.writeln("// %s is a Lexer matching the patterns of a SyntaxMap in order", name)
+	g.writeln("func %s(src string, index int, longest bool, eof bool) (Trait, int, bool) {", name)
+	g.writeln("trait, length := None, -1")
+	g.writeln("for _, pt := range %sPatterns {", prefix)
+	g.writeln("match, more := pt.match(src[index:], eof)")
+	g.writeln("if more {")
+	g.writeln("return None, -1, true")
+	g.writeln("}")
+	g.writeln("if match > length {")
+	g.writeln("trait, length = pt.trait, match")
+	g.writeln("if !longest {")
+	g.writeln("break")
+	g.writeln("}")
+	g.writeln("}")
+	g.writeln("}")
+	g.writeln("return trait, length, false")
+	g.writeln("}")
+	g.writeln("")
+
+	g.writeln("var %sPatterns = []struct {", prefix)
+	g.writeln("trait Trait")
+	g.writeln("match func(string, bool) (int, bool)")
+	g.writeln("}{")
+	for i, pt := range sm {
+		g.writeln("{%s, %s%d},", traitIdent(pt.Trait), prefix, i)
+	}
+	g.writeln("}")
+
+	for i, pt := range sm {
+		if pt.Nest != nil {
+			g.writeln("")
+			g.writeln("// %s %s", pt.Nest.Open, pt.Nest.Close)
+			g.writeNest(fmt.Sprintf("%s%d", prefix, i), *pt.Nest)
+			continue
+		}
+		if pt.Regex.dfa == nil {
+			return fmt.Errorf("Pattern %d \"%s\" is not determinized, it cannot be generated", i, pt.Regex.Src)
+		}
+		g.writeln("")
+		g.writeln("// %s", strings.ReplaceAll(pt.Regex.Src, "\n", `\n`))
+		g.writeDfa(fmt.Sprintf("%s%d", prefix, i), pt.Regex.dfa)
+	}
+
+	src, err := format.Source([]byte(g.sb.String()))
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(src)
+	return err
+}
+
+// Go expression of the trait, the constant of a builtin trait or the registration of a
+// grammar trait
+func traitIdent(trait Trait) string {
+	if int(trait) < len(traitIdents) {
+		return traitIdents[trait]
+	}
+	return fmt.Sprintf("TraitNamed(%q)", trait.Repr())
+}
+
+func (g *lexerGen) writeln(f string, args ...interface{}) {
+	g.sb.WriteString(fmt.Sprintf(f, args...))
+	g.sb.WriteByte('\n')
+}
+
+// Function returning the length of the match at the beginning of src, as dfa.MatchMore
+func (g *lexerGen) writeDfa(name string, d *dfa) {
+	g.writeln("func %s(src string, eof bool) (int, bool) {", name)
+	g.writeln("match, state := -1, 0")
+	g.writeln("for i := 0; i < len(src); i++ {")
+	g.writeln("c := src[i]")
+	g.writeln("switch state {")
+	for id := range d.states {
+		g.writeln("case %d:", id)
+		g.writeState(d, id)
+	}
+	g.writeln("}")
+	g.writeln("}")
+	g.writeln("if !eof {")
+	g.writeln("return match, true")
+	g.writeln("}")
+
+	eofs := make([]string, 0, len(d.states))
+	for id, s := range d.states {
+		if s.eof {
+			eofs = append(eofs, fmt.Sprint(id))
+		}
+	}
+	if len(eofs) != 0 {
+		g.writeln("switch state {")
+		g.writeln("case %s:", strings.Join(eofs, ", "))
+		g.writeln("return len(src), false")
+		g.writeln("}")
+	}
+	g.writeln("return match, false")
+	g.writeln("}")
+}
+
+// Function returning the length of the block at the beginning of src, as Nesting.MatchMore
+func (g *lexerGen) writeNest(name string, nt Nesting) {
+	g.writeln("func %s(src string, eof bool) (int, bool) {", name)
+	g.writeln("return Nesting{%q, %q}.MatchMore(src, 0, eof)", nt.Open, nt.Close)
+	g.writeln("}")
+}
+
+// Cases of the bytes grouped by outcome, the outcome of the most bytes is the default
+func (g *lexerGen) writeState(d *dfa, id int) {
+	s := &d.states[id]
+	ranges := make(map[lexerEdge][][2]int)
+	order := make([]lexerEdge, 0, 4)
+	counts := make(map[lexerEdge]int)
+
+	for c := 0; c < 256; c++ {
+		class := d.classes[c]
+		edge := lexerEdge{s.accept[class], s.next[class]}
+		rs, found := ranges[edge]
+		if !found {
+			order = append(order, edge)
+		}
+		if last := len(rs) - 1; last >= 0 && rs[last][1] == c-1 {
+			rs[last][1] = c
+		} else {
+			rs = append(rs, [2]int{c, c})
+		}
+		ranges[edge] = rs
+		counts[edge]++
+	}
+
+	def := order[0]
+	for _, edge := range order {
+		if counts[edge] > counts[def] {
+			def = edge
+		}
+	}
+	if len(order) == 1 {
+		g.writeEdge(def)
+		return
+	}
+
+	g.writeln("switch {")
+	for _, edge := range order {
+		if edge == def {
+			continue
+		}
+		conds := make([]string, 0, len(ranges[edge]))
+		for _, r := range ranges[edge] {
+			if r[0] == r[1] {
+				conds = append(conds, fmt.Sprintf("c == %s", byteLiteral(r[0])))
+			} else {
+				conds = append(conds, fmt.Sprintf("%s <= c && c <= %s", byteLiteral(r[0]), byteLiteral(r[1])))
+			}
+		}
+		g.writeln("case %s:", strings.Join(conds, ", "))
+		g.writeEdge(edge)
+	}
+	g.writeln("default:")
+	g.writeEdge(def)
+	g.writeln("}")
+}
+
+func (g *lexerGen) writeEdge(edge lexerEdge) {
+	switch {
+	case edge.accept && edge.next < 0:
+		g.writeln("return i, false")
+	case edge.next < 0:
+		g.writeln("return match, false")
+	case edge.accept:
+		g.writeln("match, state = i, %d", edge.next)
+	default:
+		g.writeln("state = %d", edge.next)
+	}
+}
+
+func byteLiteral(c int) string {
+	switch {
+	case c == '\'' || c == '\\':
+		return fmt.Sprintf(`'\%c'`, c)
+	case c == '\n':
+		return `'\n'`
+	case c == '\t':
+		return `'\t'`
+	case ' ' <= c && c < 0x7f:
+		return fmt.Sprintf("'%c'", c)
+	default:
+		return fmt.Sprintf("0x%02x", c)
+	}
+}
@@ -2,4 +2,4 @@ module github.com/douidik/bee
 
 go 1.18
 
-require golang.org/x/exp v0.0.0-20230310171629-522b1b587ee0 // indirect
+require golang.org/x/exp v0.0.0-20230310171629-522b1b587ee0
@@ -3,19 +3,23 @@ package main
 type IntExpr struct {
 	Value uint64
 	Type  Atom
+	Span
 }
 
 type FloatExpr struct {
 	Value float64
 	Type  Atom
+	Span
 }
 
 type StrExpr struct {
 	Value string
+	Span
 }
 
 type CharExpr struct {
 	Value byte
+	Span
 }
 
 func (int IntExpr) Result() Type {
@@ -4,12 +4,18 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
-	// "strconv"
 )
 
 func main() {
 	args := os.Args[1:]
 
+	if len(args) >= 1 && args[0] == "regex" {
+		os.Exit(regexCommand(args[1:], os.Stdout, os.Stderr))
+	}
+	if len(args) >= 1 && args[0] == "tokens" {
+		os.Exit(tokensCommand(args[1:], os.Stdout, os.Stderr))
+	}
+
 	if len(args) < 1 {
 		fmt.Println(`No sources specified in the command line arguments`)
 		os.Exit(1)
@@ -21,7 +27,7 @@ func main() {
 		os.Exit(1)
 	}
 
-	sn := NewScanner(string(src), NewBeeSyntax())
+	sn := NewLexerScanner(string(src), BeeLexer)
 	ps := NewParser(args[0], sn)
 	ast, err := ps.Parse()
 	if err != nil {
@@ -36,75 +42,6 @@ func main() {
 	fmt.Println(asm.Stream.String())
 }
 
-// func max(a, b int) int {
-// 	if a > b {
-// 		return a
-// 	} else {
-// 		return b
-// 	}
-// }
-
-// func main() {
-// 	args := os.Args[1:]
-
-// 	if len(args) < 1 {
-// 		fmt.Println(`No sources specified in the command line arguments`)
-// 		os.Exit(1)
-// 	}
-
-// 	src, err := os.ReadFile(args[0])
-// 	if err != nil {
-// 		fmt.Println(err)
-// 		os.Exit(1)
-// 	}
-
-// 	sn := NewScanner(string(src), NewBeeSyntax())
-// 	toks := []Token{}
-// 	maxLen := 0
-
-// 	for !sn.Finished() {
-// 		toks = append(toks, sn.Tokenize())
-// 	}
-// 	for _, tok := range toks {
-// 		maxLen = max(maxLen, len(tok.Expr))
-// 	}
-// 	for _, tok := range toks {
-// 		fmt.Printf("%*s : %s\n", maxLen, strconv.Quote(tok.Expr), BeeTraitName(tok.Trait))
-// 	}
-// }
-
-// func main() {
-// 	args := os.Args[1:]
-
-// 	switch len(args) {
-// 	case 0:
-// 		fmt.Printf(`No regex source given to the program`)
-// 		os.Exit(1)
-// 	case 1:
-// 		rx, err := NewRegex(args[0])
-// 		if err != nil {
-// 			fmt.Println(err)
-// 			os.Exit(1)
-// 		}
-// 		fmt.Printf(`%s`, rx.Graph(args[0]))
-
-// 	default: // 2 or more arguments (Rest is just ignored)
-// 		src, expr := args[0], args[1]
-
-// 		rx, err := NewRegex(src)
-// 		if err != nil {
-// 			fmt.Println(err)
-// 			os.Exit(1)
-// 		}
-// 		match := rx.Match(expr)
-// 		if match < 0 {
-// 			fmt.Printf(`"%s" -> "%s" :: no-match :(`, src, expr)
-// 		} else {
-// 			fmt.Printf(`"%s" -> "%s" :: %s`, src, expr, expr[:match])
-// 		}
-// 	}
-// }
-
 // func main() {
 // 	fmt.Println("Bee")
 // }
@@ -2,6 +2,7 @@ package main
 
 type Node interface {
 	Result() Type
+	Source() Span
 
 	// Dump(w io.Writer, depth uint)
 	// Graph(w io.Writer)
@@ -39,6 +40,16 @@ func Precedence(n Node) uint {
 	return 0
 }
 
+// Positions of the first and last tokens of a node
+type Span struct {
+	Begin Position
+	End   Position
+}
+
+func (sp Span) Source() Span {
+	return sp
+}
+
 type Order uint
 
 const (
@@ -48,31 +59,37 @@ const (
 
 type Reference struct {
 	Def Def
+	Span
 }
 
 type Cast struct {
 	Operand Node
 	Type    Type
+	Span
 }
 
 type Nest struct {
 	Body []Node
+	Span
 }
 
 type Compound struct {
 	Scope *Scope
 	Body  []Node
+	Span
 }
 
 type If struct {
 	Conds Compound
 	If    Compound
 	Else  Compound
+	Span
 }
 
 type For struct {
 	Conds Compound
 	Body  Compound
+	Span
 }
 
 func (ref Reference) Result() Type {
@@ -3,8 +3,10 @@ package main
 import (
 	"fmt"
 	"math"
+	"sort"
 	"strconv"
 	"strings"
+	"unicode/utf8"
 
 	"golang.org/x/exp/slices"
 )
@@ -13,8 +15,11 @@ type Parser struct {
 	name      string
 	sn        Scanner
 	peekQueue []Token
-	ast       Ast
-	scope     *Scope
+	last      Token
+	// Lexical errors of the tokens scanned so far
+	lexErrs []error
+	ast     Ast
+	scope   *Scope
 }
 
 func NewParser(name string, sn Scanner) Parser {
@@ -37,21 +42,39 @@ func (ps *Parser) Parse() (*Ast, error) {
 	// ps.scope.Add(&Typedef{Name: "f32", Type: &Atom{size: 4, float: true}})
 	// ps.scope.Add(&Typedef{Name: "f64", Type: &Atom{size: 8, float: true}})
 
-	for !ps.sn.Finished() {
+	for !ps.finished() {
 		node, err := ps.parseNode(NewLine)
 		if err != nil {
-			return nil, err
+			return nil, ps.report(err)
 		}
 		ps.ast.Body = append(ps.ast.Body, node)
 	}
+	if len(ps.lexErrs) != 0 {
+		return nil, ps.report(nil)
+	}
 	return &ps.ast, nil
 }
 
+// Lexical errors of the whole source and the parsing error if any, sorted by position
+func (ps *Parser) report(err error) error {
+	for !ps.finished() {
+		ps.token()
+	}
+	errs := append(ErrorList{}, ps.lexErrs...)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	sort.SliceStable(errs, func(i, j int) bool {
+		return errs.offset(i) < errs.offset(j)
+	})
+	return errs
+}
+
 func (ps *Parser) parseNode(delim Trait) (Node, error) {
 	var head Node = nil
 	var last Token
 
-	for !ps.sn.Finished() {
+	for !ps.finished() {
 		if last = ps.token(delim); last.Ok {
 			break
 		}
@@ -67,6 +90,9 @@ func (ps *Parser) parseNode(delim Trait) (Node, error) {
 	}
 
 	if !last.Ok {
+		if ps.finished() {
+			last = ps.peek()
+		}
 		return nil, ps.errorf(last, "Expected <%s> got <%s>", delim.Repr(), last.Trait.Repr())
 	}
 	if head != nil {
@@ -77,7 +103,7 @@ func (ps *Parser) parseNode(delim Trait) (Node, error) {
 }
 
 func (ps *Parser) expectNode(prev Node, delim Trait) (Node, error) {
-	if ps.token(KwIf).Ok {
+	if kw := ps.token(KwIf); kw.Ok {
 		var (
 			i   If
 			err error
@@ -93,10 +119,11 @@ func (ps *Parser) expectNode(prev Node, delim Trait) (Node, error) {
 				return nil, err
 			}
 		}
+		i.Span = ps.span(kw.Pos)
 		return i, nil
 	}
 
-	if ps.token(KwFor).Ok {
+	if kw := ps.token(KwFor); kw.Ok {
 		var (
 			f   For
 			err error
@@ -107,13 +134,14 @@ func (ps *Parser) expectNode(prev Node, delim Trait) (Node, error) {
 		if f.Body, err = ps.parseCompound(NewLine, ScopeEnd); err != nil {
 			return nil, err
 		}
+		f.Span = ps.span(kw.Pos)
 		return f, nil
 	}
 
 	if id := ps.token(Identifier); id.Ok {
 		def := ps.scope.Search(id.Expr)
 		if def != nil {
-			return Reference{Def: def}, nil
+			return Reference{Def: def, Span: Span{id.Pos, id.Pos}}, nil
 		}
 
 		if cast, casted := def.(Type); casted && ps.token(ParenBegin).Ok {
@@ -124,7 +152,7 @@ func (ps *Parser) expectNode(prev Node, delim Trait) (Node, error) {
 			if !node.Result().Cast(cast) {
 				return nil, ps.errorf(id, "Cannot cast expression to type '%s'", id.Expr)
 			}
-			return Cast{node, cast}, nil
+			return Cast{node, cast, ps.span(id.Pos)}, nil
 		}
 
 		if init := ps.token(Define, Declare); init.Ok {
@@ -133,11 +161,12 @@ func (ps *Parser) expectNode(prev Node, delim Trait) (Node, error) {
 				return nil, err
 			}
 			def = ps.scope.Add(&Var{Name: id.Expr, Type: expr.Result()})
+			span := Span{id.Pos, expr.Source().End}
 			switch init.Trait {
 			case Define:
-				return DefineExpr{def, expr}, nil
+				return DefineExpr{def, expr, span}, nil
 			case Declare:
-				return DeclareExpr{def, expr}, nil
+				return DeclareExpr{def, expr, span}, nil
 			}
 		}
 		return nil, ps.errorf(id, "Use of undeclared identifier")
@@ -151,7 +180,7 @@ func (ps *Parser) expectNode(prev Node, delim Trait) (Node, error) {
 		case Str:
 			content = UnescapeStr(str.Expr[1 : len(str.Expr)-1])
 		}
-		return StrExpr{content}, nil
+		return StrExpr{content, Span{str.Pos, str.Pos}}, nil
 	}
 
 	// Integers and floats constants infers to 32-64 bits depending on the value size
@@ -176,7 +205,7 @@ func (ps *Parser) expectNode(prev Node, delim Trait) (Node, error) {
 		} else {
 			size = 4
 		}
-		return IntExpr{uint64(n), Atom{size: uint64(size), signed: true}}, err
+		return IntExpr{uint64(n), Atom{size: uint64(size), signed: true}, Span{int.Pos, int.Pos}}, err
 	}
 
 	if float := ps.token(Float); float.Ok {
@@ -188,7 +217,7 @@ func (ps *Parser) expectNode(prev Node, delim Trait) (Node, error) {
 		} else {
 			size = 4
 		}
-		return FloatExpr{float64(f), Atom{size: size, float: true}}, err
+		return FloatExpr{float64(f), Atom{size: size, float: true}, Span{float.Pos, float.Pos}}, err
 	}
 
 	if char := ps.token(Char); char.Ok {
@@ -197,7 +226,7 @@ func (ps *Parser) expectNode(prev Node, delim Trait) (Node, error) {
 		case 0:
 			return nil, ps.errorf(char, "Empty character constant")
 		case 1:
-			return CharExpr{content[0]}, nil
+			return CharExpr{content[0], Span{char.Pos, char.Pos}}, nil
 		default:
 			return nil, ps.errorf(char, "Character constant too long")
 		}
@@ -209,7 +238,7 @@ func (ps *Parser) expectNode(prev Node, delim Trait) (Node, error) {
 			if err != nil {
 				return nil, err
 			}
-			return UnaryExpr{OrderPrev, expr, sign}, nil
+			return UnaryExpr{OrderPrev, expr, sign, Span{sign.Pos, expr.Source().End}}, nil
 		}
 	}
 
@@ -232,12 +261,12 @@ func (ps *Parser) expectNode(prev Node, delim Trait) (Node, error) {
 		if !prev.Result().Cast(next.Result()) {
 			return nil, ps.errorf(bin, "Incompatible operands in binary expression")
 		}
-		return BinaryExpr{[2]Node{prev, next}, bin}, nil
+		return BinaryExpr{[2]Node{prev, next}, bin, Span{prev.Source().Begin, next.Source().End}}, nil
 	}
 
 	if incr := ps.token(Increment, Decrement); incr.Ok {
 		if prev != nil {
-			return UnaryExpr{OrderPost, prev, incr}, nil
+			return UnaryExpr{OrderPost, prev, incr, Span{prev.Source().Begin, incr.Pos}}, nil
 		} else {
 			next, err := ps.expectNode(nil, delim)
 			if err != nil {
@@ -246,24 +275,27 @@ func (ps *Parser) expectNode(prev Node, delim Trait) (Node, error) {
 			if next == nil {
 				return nil, ps.errorf(incr, "Missing expression for increment")
 			}
-			return UnaryExpr{OrderPrev, next, incr}, nil
+			return UnaryExpr{OrderPrev, next, incr, Span{incr.Pos, next.Source().End}}, nil
 		}
 	}
 
-	if ps.token(ScopeBegin).Ok {
-		return ps.parseCompound(NewLine, ScopeEnd)
+	if brace := ps.token(ScopeBegin); brace.Ok {
+		compound, err := ps.parseCompound(NewLine, ScopeEnd)
+		compound.Begin = brace.Pos
+		return compound, err
 	}
 
-	if ps.token(ParenBegin).Ok {
+	if paren := ps.token(ParenBegin); paren.Ok {
 		nest := Nest{Body: make([]Node, 0)}
 
-		for !ps.sn.Finished() && !ps.token(ParenEnd).Ok {
+		for !ps.finished() && !ps.token(ParenEnd).Ok {
 			node, err := ps.parseNode(Comma)
 			if err != nil {
 				return nest, err
 			}
 			nest.Body = append(nest.Body, node)
 		}
+		nest.Span = ps.span(paren.Pos)
 		return nest, nil
 	}
 
@@ -273,8 +305,9 @@ func (ps *Parser) expectNode(prev Node, delim Trait) (Node, error) {
 func (ps *Parser) parseCompound(delim, end Trait) (Compound, error) {
 	ps.scope = NewScope(ps.scope)
 	compound := Compound{Scope: ps.scope, Body: make([]Node, 0)}
+	begin := ps.peek().Pos
 
-	for !ps.sn.Finished() && !ps.token(end).Ok {
+	for !ps.finished() && !ps.token(end).Ok {
 		node, err := ps.parseNode(delim)
 		if err != nil {
 			return compound, err
@@ -283,6 +316,7 @@ func (ps *Parser) parseCompound(delim, end Trait) (Compound, error) {
 	}
 
 	ps.scope = ps.scope.Owner
+	compound.Span = ps.span(begin)
 	return compound, nil
 }
 
@@ -292,72 +326,81 @@ func (ps *Parser) token(traits ...Trait) Token {
 		tok = ps.peekQueue[0]
 		ps.peekQueue = ps.peekQueue[1:]
 	} else {
-		tok = ps.sn.Tokenize()
+		tok = ps.scan()
 	}
 	tok.Ok = len(traits) == 0 || slices.Contains(traits[:], tok.Trait)
 	if !tok.Ok {
 		ps.peekQueue = append(ps.peekQueue, tok)
+	} else {
+		ps.last = tok
+	}
+	return tok
+}
+
+// Next token without consuming it
+func (ps *Parser) peek() Token {
+	if len(ps.peekQueue) == 0 {
+		ps.peekQueue = append(ps.peekQueue, ps.scan())
+	}
+	return ps.peekQueue[0]
+}
+
+func (ps *Parser) finished() bool {
+	return ps.peek().Trait == Eof
+}
+
+// Next token of the scanner, lexical errors are recorded and skipped
+func (ps *Parser) scan() Token {
+	tok := ps.sn.Tokenize()
+	for tok.Trait.Invalid() {
+		ps.lexErrs = append(ps.lexErrs, ps.errorf(tok, "%s", tok.Trait.Reason()))
+		tok = ps.sn.Tokenize()
 	}
 	return tok
 }
 
+// Span from the position to the last token consumed
+func (ps *Parser) span(begin Position) Span {
+	return Span{begin, ps.last.Pos}
+}
+
 //	Example: from 'basic.bee':24 > foo :: fn () -> {
 //	                                               ^ Function return type expected in signature after '->'
 
 func (ps *Parser) errorf(tok Token, f string, args ...any) error {
-	trim := func(str string, index, end int) int {
-		step := func(a, b int) int {
-			d := b - a
-			if d > 0 {
-				return +1
-			} else {
-				return -1
-			}
-		}
-		// Search new line in the str[index : end] interval
-		newLine := index
-		for newLine != end && str[newLine] != '\n' {
-			newLine += step(index, end)
-		}
-		// Search first non-blank character in the str[newLine : index] interval
-		begin := newLine
-		for begin != index && strings.IndexByte(" \t\n\v\f\r", str[begin]) != -1 {
-			begin += step(newLine, index)
-		}
-		return begin
-	}
-
-	// todo: trim function not required, just take a slice and rebase the index
-	src := ps.sn.src
-	begin := trim(src, tok.Index, 0)
-	end := begin + strings.IndexByte(src[begin:], '\n')
-	line := 1 + strings.Count(src[:begin], "\n")
-	location := fmt.Sprintf("from '%s':%d > ", ps.name, line)
-	snippet := src[begin:end]
-	cursor := len(location) + (tok.Index - begin + 1)
+	line, _ := ps.sn.Line(tok.Pos)
+	snippet := strings.TrimLeft(line, " \t\n\v\f\r")
+	location := fmt.Sprintf("from '%s':%d > ", ps.name, tok.Pos.Line)
+	cursor := len(location) + tok.Pos.Col - utf8.RuneCountInString(line[:len(line)-len(snippet)])
 	reason := fmt.Sprintf(f, args...)
-	return fmt.Errorf("%s%s\n%*c %s", location, snippet, cursor, '^', reason)
+	return SourceError{tok.Pos, fmt.Sprintf("%s%s\n%*c %s", location, snippet, cursor, '^', reason)}
+}
+
+// Error at a position of the source, the message shows its line with a caret
+type SourceError struct {
+	Pos Position
+	msg string
+}
+
+func (err SourceError) Error() string {
+	return err.msg
 }
 
-// func (ps *Parser) errorf(tok Token, f string, args ...any) error {
-// 	var (
-// 		src   = ps.sn.src
-// 		begin int
-// 		end   int
-// 		line  int
-// 	)
-
-// 	if begin = strings.LastIndexByte(src[:tok.Index], '\n'); begin < 0 {
-// 		begin = 0
-// 	}
-// 	if end = strings.IndexByte(src[:tok.Index], '\n'); end < 0 {
-// 		end = len(src)
-// 	}
-// 	line = strings.Count(src[:begin], "\n")
-
-// 	location := fmt.Sprintf("%s:%d > ", ps.name, line)
-// 	snippet := strings.Trim(src[begin:end], " \t\n\v\f\r")
-// 	offset := len(location) + (tok.Index - begin + 1)
-// 	reason := fmt.Sprintf(f, args...)
-// 	return fmt.Errorf("%s%s\n%*c %s", location, snippet, offset, '^', reason)
-// }
+// Errors of a source, one per line
+type ErrorList []error
+
+func (errs ErrorList) Error() string {
+	msgs := make([]string, 0, len(errs))
+	for _, err := range errs {
+		msgs = append(msgs, err.Error())
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// Offset of the error, errors without position go last
+func (errs ErrorList) offset(i int) int {
+	if err, ok := errs[i].(SourceError); ok {
+		return err.Pos.Offset
+	}
+	return math.MaxInt
+}
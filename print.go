@@ -0,0 +1,713 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Regenerates bee regex source from a node graph. Sequences and alternations are
+// recovered with the post-dominators of each node, loops from the shape of their
+// head: star {B X}, wave {post pre}, lazy star {X B} and plus whose leaves jump back
+// to the head
+
+type termKind uint
+
+const (
+	termAtom termKind = iota
+	termPost
+	termSeq
+	termAlt
+	termWave
+)
+
+type term struct {
+	src  string
+	kind termKind
+}
+
+// Source form of the terms, bee regex or another dialect
+type printSyntax interface {
+	seq(items []term) term
+	alt(alts []term) term
+	wave(pre term, post term) term
+	post(operand term, op string) term
+	group(name string, inner term) term
+	modifier(flags matchFlag, t term) term
+	state(s *state) (term, error)
+}
+
+type beeSyntax struct{}
+
+// View of the graph, the successors of a node go to the end node once the view has
+// reached its stop. Loops get a nested view where the edges to the head reach the end
+type printer struct {
+	syntax printSyntax
+	parent *printer
+	head   *node
+	end    *node
+	// Nodes of the loop body, able to reach the head in the parent view
+	loop map[*node]bool
+	// Post-dominators of the nodes reached so far and the nodes never reaching the end,
+	// see postDominators
+	pdom  map[*node]map[*node]bool
+	stuck map[*node]bool
+	succs map[*node]nodes
+}
+
+// Index of the epsilon nodes made by the printer for the alternations of several nodes
+const forkIndex = -1
+
+var setTokens = map[string]byte{
+	setBlank:  '_',
+	setAlpha:  'a',
+	setOp:     'o',
+	setNum:    'n',
+	setQuote:  'Q',
+	setSQuote: 'q',
+}
+
+var classTokens = map[string]byte{
+	"Letter": 'A',
+	"Digit":  'D',
+}
+
+// Canonical source of the graph, parsing it again gives an equivalent graph printed
+// the same way
+func (rx *Regex) Canonical() (string, error) {
+	if rx.Head == nil {
+		return "", nil
+	}
+	t, err := printGraph(rx.Head, beeSyntax{})
+	return t.src, err
+}
+
+func printGraph(head *node, syntax printSyntax) (term, error) {
+	pr := &printer{syntax: syntax, end: &node{}}
+	return pr.print(head, pr.end)
+}
+
+func errorPrint(desc string, args ...interface{}) error {
+	return fmt.Errorf("Graph has no bee regex form, "+desc, args...)
+}
+
+// Successors of n in the view, computed once per node
+func (pr *printer) succ(n *node) nodes {
+	if succ, found := pr.succs[n]; found {
+		return succ
+	}
+	if pr.succs == nil {
+		pr.succs = make(map[*node]nodes)
+	}
+	succ := pr.successors(n)
+	pr.succs[n] = succ
+	return succ
+}
+
+func (pr *printer) successors(n *node) nodes {
+	if n == pr.end {
+		return nil
+	}
+
+	succ := make(nodes, 0, len(n.edges)+1)
+	if pr.parent == nil {
+		succ = append(succ, n.edges...)
+		if n.index != forkIndex && !n.Branch() {
+			succ = append(succ, pr.end)
+		}
+		return succ.unique()
+	}
+
+	// Leaves of the loop jump to the head or exit, an edge to the head without exits
+	// belongs to a nested loop sharing the head
+	parent := pr.parent.succ(n)
+	jumps, exits := false, false
+	for _, s := range parent {
+		jumps = jumps || s == pr.head
+		exits = exits || !pr.loop[s]
+	}
+	for _, s := range parent {
+		switch {
+		case !jumps || !exits:
+			succ = append(succ, s)
+		case s == pr.head:
+			succ = append(succ, pr.end)
+		case !pr.loop[s]:
+			// Exit of the loop, reached again from the head
+		default:
+			succ = append(succ, s)
+		}
+	}
+	return succ.unique()
+}
+
+// Nodes reachable from n in the view, n included
+func (pr *printer) reach(n *node) nodes {
+	seen := map[*node]bool{n: true}
+	reached := nodes{n}
+	for i := 0; i < len(reached); i++ {
+		for _, s := range pr.succ(reached[i]) {
+			if !seen[s] {
+				seen[s] = true
+				reached = append(reached, s)
+			}
+		}
+	}
+	return reached
+}
+
+func (pr *printer) cyclic(n *node) bool {
+	for _, s := range pr.succ(n) {
+		for _, r := range pr.reach(s) {
+			if r == n {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (pr *printer) newLoop(head *node) *printer {
+	loop := &printer{syntax: pr.syntax, parent: pr, head: head, end: &node{}, loop: map[*node]bool{head: true}}
+	region := pr.reach(head)
+
+	for changed := true; changed; {
+		changed = false
+		for _, n := range region {
+			if loop.loop[n] {
+				continue
+			}
+			for _, s := range pr.succ(n) {
+				if loop.loop[s] {
+					loop.loop[n], changed = true, true
+					break
+				}
+			}
+		}
+	}
+	return loop
+}
+
+// Post-dominators of n closest first, the nodes every path from n to the end goes through
+func (pr *printer) postDominators(n *node) nodes {
+	if pr.pdom == nil {
+		pr.pdom = make(map[*node]map[*node]bool)
+		pr.stuck = make(map[*node]bool)
+	}
+	if pr.pdom[n] == nil && !pr.stuck[n] {
+		pr.dominate(pr.reach(n))
+	}
+	pdom := pr.pdom
+
+	doms := make(nodes, 0, len(pdom[n]))
+	for d := range pdom[n] {
+		if d != n {
+			doms = append(doms, d)
+		}
+	}
+	sort.Slice(doms, func(i, j int) bool {
+		return len(pdom[doms[i]]) > len(pdom[doms[j]])
+	})
+	return doms
+}
+
+// Post-dominators of the region nodes not computed yet, they only depend on the nodes
+// reached and the region is walked backward from its leaves. Nodes never reaching the
+// end are post-dominated by any node and left out of the intersections
+func (pr *printer) dominate(region nodes) {
+	fresh := make(nodes, 0, len(region))
+	reaches := make(map[*node]bool, len(region))
+	for _, r := range region {
+		if pr.pdom[r] != nil {
+			reaches[r] = true
+		} else if !pr.stuck[r] {
+			fresh = append(fresh, r)
+		}
+	}
+	for changed := true; changed; {
+		changed = false
+		for i := len(fresh) - 1; i >= 0; i-- {
+			r := fresh[i]
+			if reaches[r] {
+				continue
+			}
+			if r == pr.end {
+				reaches[r], changed = true, true
+				continue
+			}
+			for _, s := range pr.succ(r) {
+				if reaches[s] {
+					reaches[r], changed = true, true
+					break
+				}
+			}
+		}
+	}
+
+	for _, r := range fresh {
+		if !reaches[r] {
+			pr.stuck[r] = true
+			continue
+		}
+		pr.pdom[r] = make(map[*node]bool, len(region))
+		if r == pr.end {
+			pr.pdom[r][r] = true
+			continue
+		}
+		for _, other := range region {
+			pr.pdom[r][other] = true
+		}
+	}
+
+	for changed := true; changed; {
+		changed = false
+		for i := len(fresh) - 1; i >= 0; i-- {
+			r := fresh[i]
+			if r == pr.end || !reaches[r] {
+				continue
+			}
+			for d := range pr.pdom[r] {
+				if d == r {
+					continue
+				}
+				for _, s := range pr.succ(r) {
+					if reaches[s] && !pr.pdom[s][d] {
+						delete(pr.pdom[r], d)
+						changed = true
+						break
+					}
+				}
+			}
+		}
+	}
+}
+
+func (pr *printer) join(n *node) (*node, error) {
+	doms := pr.postDominators(n)
+	if len(doms) == 0 {
+		return nil, errorPrint("the paths from node %d never join", n.index)
+	}
+	return doms[0], nil
+}
+
+// Sequence of terms from n until stop
+func (pr *printer) print(n *node, stop *node) (term, error) {
+	items := make([]term, 0, 4)
+
+	for n != stop {
+		if n == pr.end {
+			return term{}, errorPrint("node %d is not inside its sequence", n.index)
+		}
+
+		if pr.cyclic(n) {
+			t, next, err := pr.printLoop(n)
+			if err != nil {
+				return term{}, err
+			}
+			items = append(items, t)
+			n = next
+			continue
+		}
+
+		if n.state.Tag == begin {
+			t, end, err := pr.printGroup(n)
+			if err != nil {
+				return term{}, err
+			}
+			items = append(items, t)
+			n = end
+		} else {
+			t, err := pr.syntax.state(&n.state)
+			if err != nil {
+				return term{}, err
+			}
+			if n.state.flags != 0 {
+				t = pr.syntax.modifier(n.state.flags, t)
+			}
+			if t.src != "" {
+				items = append(items, t)
+			}
+		}
+
+		succ := pr.succ(n)
+		if len(succ) == 1 {
+			n = succ[0]
+			continue
+		}
+
+		join, err := pr.join(n)
+		if err != nil {
+			return term{}, err
+		}
+		t, err := pr.printAlt(succ, join)
+		if err != nil {
+			return term{}, err
+		}
+		items = append(items, t)
+		n = join
+	}
+
+	return pr.syntax.seq(items), nil
+}
+
+// An empty last alternative is a quest, an empty first alternative a lazy quest
+func (pr *printer) printAlt(succ nodes, join *node) (term, error) {
+	alts := make([]term, 0, len(succ))
+	lazy := false
+	for i, s := range succ {
+		t, err := pr.print(s, join)
+		if err != nil {
+			return term{}, err
+		}
+		switch {
+		case t.src != "":
+			alts = append(alts, t)
+		case i == 0 && !lazy:
+			lazy = true
+		case i != len(succ)-1 || lazy:
+			return term{}, errorPrint("an empty alternative is tried before node %d", succ[i].index)
+		}
+	}
+
+	switch {
+	case lazy:
+		return pr.syntax.post(pr.syntax.alt(alts), "??"), nil
+	case len(alts) == len(succ):
+		return pr.syntax.alt(alts), nil
+	default:
+		return pr.syntax.post(pr.syntax.alt(alts), "?"), nil
+	}
+}
+
+// Capture group from the begin node up to the closest end node of the same group
+func (pr *printer) printGroup(n *node) (term, *node, error) {
+	succ := pr.succ(n)
+	if len(succ) != 1 {
+		return term{}, nil, errorPrint("group %d does not begin a sequence", n.state.group)
+	}
+
+	for _, d := range pr.postDominators(n) {
+		if d.state.Tag != end || d.state.group != n.state.group {
+			continue
+		}
+		t, err := pr.print(succ[0], d)
+		if err != nil {
+			return term{}, nil, err
+		}
+		return pr.syntax.group(n.state.str, t), d, nil
+	}
+	return term{}, nil, errorPrint("group %d is not closed", n.state.group)
+}
+
+// Loop with its head at n, returns the node following the loop
+func (pr *printer) printLoop(n *node) (term, *node, error) {
+	loop := pr.newLoop(n)
+	succ := pr.succ(n)
+
+	last := len(succ) - 1
+	if n.state.Tag == epsilon && last > 0 && loop.within(succ[:last]) && !loop.loop[succ[last]] {
+		body, err := loop.printFrom(succ[:last], loop.end)
+		if err != nil {
+			return term{}, nil, err
+		}
+		return pr.syntax.post(body, "*"), succ[last], nil
+	}
+
+	if n.state.Tag == epsilon && last > 0 && loop.within(succ[1:]) && !loop.loop[succ[0]] && loop.lazy(succ[0]) {
+		body, err := loop.printFrom(succ[1:], loop.end)
+		if err != nil {
+			return term{}, nil, err
+		}
+		return pr.syntax.post(body, "*?"), succ[0], nil
+	}
+
+	if n.state.Tag == epsilon && last > 0 && loop.within(succ[1:]) && !loop.loop[succ[0]] {
+		pre, err := loop.printWave(succ[1:])
+		if err != nil {
+			return term{}, nil, err
+		}
+		join, err := pr.join(n)
+		if err != nil {
+			return term{}, nil, err
+		}
+		post, err := pr.print(succ[0], join)
+		if err != nil {
+			return term{}, nil, err
+		}
+		return pr.syntax.wave(pre, post), join, nil
+	}
+
+	// The leaves of the body leave the loop through the same nodes
+	var exits nodes
+	for _, leaves := range loop.jumpExits() {
+		if len(leaves) == 0 {
+			continue
+		}
+		if exits != nil && !exits.equal(leaves) {
+			return term{}, nil, errorPrint("the loop at node %d has no single exit", n.index)
+		}
+		exits = leaves
+	}
+
+	if exits == nil {
+		return term{}, nil, errorPrint("the loop at node %d has no exit", n.index)
+	}
+	body, err := loop.print(n, loop.end)
+	if err != nil {
+		return term{}, nil, err
+	}
+	return pr.syntax.post(body, "+"), fork(exits), nil
+}
+
+// Nodes outside of the loop following each member jumping back to the head
+func (pr *printer) jumpExits() []nodes {
+	exits := make([]nodes, 0, 2)
+	for member := range pr.loop {
+		jumps := false
+		leaves := make(nodes, 0, 2)
+		for _, s := range pr.parent.succ(member) {
+			switch {
+			case s == pr.head:
+				jumps = true
+			case !pr.loop[s]:
+				leaves = append(leaves, s)
+			}
+		}
+		if jumps {
+			exits = append(exits, leaves)
+		}
+	}
+	return exits
+}
+
+// Whether the leaves of the body jumping back to the head leave the loop as the exit
+// node tried first from the head does, unlike the none states of a wave
+func (pr *printer) lazy(exit *node) bool {
+	follow := nodes{exit}
+	if exit.state.Tag == epsilon {
+		follow = pr.parent.succ(exit)
+	}
+	for _, leaves := range pr.jumpExits() {
+		if len(leaves) != 0 && !leaves.equal(follow) && !leaves.equal(nodes{exit}) {
+			return false
+		}
+	}
+	return true
+}
+
+func (pr *printer) within(s nodes) bool {
+	for _, n := range s {
+		if !pr.loop[n] {
+			return false
+		}
+	}
+	return true
+}
+
+// Alternation of the sequences from each start node until stop
+func (pr *printer) printFrom(starts nodes, stop *node) (term, error) {
+	return pr.print(fork(starts), stop)
+}
+
+// Epsilon node trying each start node in turn, its edges keep their order
+func fork(starts nodes) *node {
+	if len(starts) == 1 {
+		return starts[0]
+	}
+	fork := newNode(newEpsilon())
+	fork.index = forkIndex
+	fork.edges = append(fork.edges, starts...)
+	return fork
+}
+
+// Body of the wave operator, its leaves jump back to the head and then fail on a none state
+func (pr *printer) printWave(starts nodes) (term, error) {
+	for _, leaves := range pr.jumpExits() {
+		for _, s := range leaves {
+			if s.state.Tag != none {
+				return term{}, errorPrint("the wave at node %d is not followed by a none state", pr.head.index)
+			}
+		}
+	}
+	return pr.printFrom(starts, pr.end)
+}
+
+func (beeSyntax) seq(items []term) term {
+	if len(items) == 1 {
+		return items[0]
+	}
+	srcs := make([]string, 0, len(items))
+	for _, item := range items {
+		srcs = append(srcs, item.src)
+	}
+	return term{strings.Join(srcs, " "), termSeq}
+}
+
+func (beeSyntax) alt(alts []term) term {
+	if len(alts) == 1 {
+		return alts[0]
+	}
+	srcs := make([]string, 0, len(alts))
+	for _, alt := range alts {
+		if alt.kind == termAlt {
+			srcs = append(srcs, alt.src)
+		} else {
+			srcs = append(srcs, alt.operand())
+		}
+	}
+	return term{strings.Join(srcs, "|"), termAlt}
+}
+
+func (beeSyntax) wave(pre term, post term) term {
+	return term{pre.operand() + "~" + post.operand(), termWave}
+}
+
+func (beeSyntax) post(operand term, op string) term {
+	return term{operand.operand() + op, termPost}
+}
+
+func (beeSyntax) group(name string, inner term) term {
+	if name != "" {
+		return term{fmt.Sprintf("(%s: %s)", name, inner.src), termAtom}
+	}
+	return term{fmt.Sprintf("(%s)", inner.src), termAtom}
+}
+
+func (beeSyntax) modifier(flags matchFlag, t term) term {
+	return term{fmt.Sprintf("{?%s: %s}", flags, t.src), termAtom}
+}
+
+// Source of the term as a single token for the operators
+func (t term) operand() string {
+	if t.kind == termAtom {
+		return t.src
+	}
+	return "{" + t.src + "}"
+}
+
+func (syntax beeSyntax) state(s *state) (term, error) {
+	switch s.Tag {
+	case epsilon, end:
+		return term{}, nil
+	case anything:
+		return term{"^", termAtom}, nil
+	case text:
+		return term{printText(s.str), termAtom}, nil
+
+	case set:
+		if tok, found := setTokens[s.str]; found {
+			return term{string(tok), termAtom}, nil
+		}
+		ranges, _ := s.charRanges()
+		return term{printScope(mergeRanges(ranges), false, s.runes), termAtom}, nil
+
+	case scope:
+		return term{printScope(mergeRanges(append([][2]rune{}, s.ranges...)), s.neg, s.runes), termAtom}, nil
+
+	case class:
+		if tok, found := classTokens[s.str]; found {
+			return term{string(tok), termAtom}, nil
+		}
+
+	case anchor:
+		return term{s.str, termAtom}, nil
+
+	case not, dash, behind:
+		seq, err := printGraph(s.seq, syntax)
+		if err != nil {
+			return term{}, err
+		}
+		switch s.Tag {
+		case not:
+			return term{"!" + seq.operand(), termAtom}, nil
+		case dash:
+			return term{"/" + seq.operand(), termAtom}, nil
+		default:
+			return term{"\\" + seq.operand(), termAtom}, nil
+		}
+	}
+
+	return term{}, errorPrint("state %d cannot be written", s.Tag)
+}
+
+func printText(str string) string {
+	var sb strings.Builder
+	sb.WriteByte('\'')
+
+	for i := 0; i < len(str); {
+		c, size := utf8.DecodeRuneInString(str[i:])
+		switch {
+		case c == utf8.RuneError && size == 1:
+			sb.WriteString(fmt.Sprintf("\\x%02x", str[i]))
+		case c == 0:
+			sb.WriteString(`\0`)
+		case c == '\f':
+			sb.WriteString(`\f`)
+		case c == '\n':
+			sb.WriteString(`\n`)
+		case c == '\r':
+			sb.WriteString(`\r`)
+		case c == '\t':
+			sb.WriteString(`\t`)
+		case c == '\v':
+			sb.WriteString(`\v`)
+		case c == '\\' || c == '\'':
+			sb.WriteByte('\\')
+			sb.WriteRune(c)
+		case c < ' ' || c == 0x7f:
+			sb.WriteString(fmt.Sprintf("\\x%02x", c))
+		default:
+			sb.WriteRune(c)
+		}
+		i += size
+	}
+
+	sb.WriteByte('\'')
+	return sb.String()
+}
+
+// Scopes have no escapes: a range starting with <]> is written first, a leading <^>
+// is moved after the other ranges and a character followed by <-> is written as a range
+func printScope(ranges [][2]rune, neg bool, runes bool) string {
+	for i, r := range ranges {
+		if r[0] == ']' {
+			ranges = append([][2]rune{r}, append(ranges[:i:i], ranges[i+1:]...)...)
+			break
+		}
+	}
+	if !neg && ranges[0][0] == '^' {
+		switch {
+		case len(ranges) > 1:
+			ranges = append(ranges[1:], ranges[0])
+		case ranges[0][1] == '^':
+			return printText("^")
+		default:
+			ranges = [][2]rune{{'_', ranges[0][1]}, {'^', '^'}}
+		}
+	}
+
+	var sb strings.Builder
+	write := func(c rune) {
+		if runes {
+			sb.WriteRune(c)
+		} else {
+			sb.WriteByte(byte(c))
+		}
+	}
+
+	sb.WriteByte('[')
+	if neg {
+		sb.WriteByte('^')
+	}
+	for i, r := range ranges {
+		dash := i+1 < len(ranges) && ranges[i+1][0] == '-'
+		write(r[0])
+		if r[0] != r[1] || dash {
+			sb.WriteByte('-')
+			write(r[1])
+		}
+	}
+	sb.WriteByte(']')
+	return sb.String()
+}
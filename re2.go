@@ -0,0 +1,410 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"regexp/syntax"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Translation between the bee dialect and the RE2 syntax of the regexp package. Both
+// prefer the first alternative that matches. Lookarounds and negations of sequences
+// have no RE2 form, the start of the input has no bee form past the beginning. Without
+// ModeRunes bee sets and scopes match bytes where RE2 matches runes, translations then
+// only agree on ASCII input
+
+type re2Syntax struct{}
+
+var re2Classes = map[string]string{
+	"Letter": `\pL`,
+	"Digit":  `\p{Nd}`,
+}
+
+var re2Anchors = map[string]string{
+	string(anchorLineBegin): `(?m:^)`,
+	string(anchorLineEnd):   `(?m:$)`,
+	string(anchorInputEnd):  `\z`,
+	string(anchorWord):      `\b`,
+}
+
+var classTables = map[byte]*unicode.RangeTable{
+	'A': unicode.Letter,
+	'D': unicode.Digit,
+}
+
+func errorTranslate(construct string, args ...interface{}) error {
+	return fmt.Errorf("No equivalent for "+construct, args...)
+}
+
+// RE2 source of the regex, matching at the beginning of the input needs a ^ anchor
+func (rx *Regex) RE2() (string, error) {
+	if rx.Head == nil {
+		return "", nil
+	}
+	t, err := printGraph(rx.Head.Clone().Simplify(), re2Syntax{})
+	return t.src, err
+}
+
+func (re2Syntax) operand(t term) string {
+	if t.kind == termAtom {
+		return t.src
+	}
+	return "(?:" + t.src + ")"
+}
+
+func (syntax re2Syntax) seq(items []term) term {
+	if len(items) == 1 {
+		return items[0]
+	}
+	var sb strings.Builder
+	for _, item := range items {
+		if item.kind == termAlt {
+			sb.WriteString(syntax.operand(item))
+		} else {
+			sb.WriteString(item.src)
+		}
+	}
+	return term{sb.String(), termSeq}
+}
+
+func (re2Syntax) alt(alts []term) term {
+	if len(alts) == 1 {
+		return alts[0]
+	}
+	srcs := make([]string, 0, len(alts))
+	for _, alt := range alts {
+		srcs = append(srcs, alt.src)
+	}
+	return term{strings.Join(srcs, "|"), termAlt}
+}
+
+func (syntax re2Syntax) wave(pre term, post term) term {
+	return syntax.seq([]term{syntax.post(pre, "*?"), post})
+}
+
+func (syntax re2Syntax) post(operand term, op string) term {
+	return term{syntax.operand(operand) + op, termPost}
+}
+
+func (re2Syntax) group(name string, inner term) term {
+	if name != "" {
+		return term{fmt.Sprintf("(?P<%s>%s)", name, inner.src), termAtom}
+	}
+	return term{fmt.Sprintf("(%s)", inner.src), termAtom}
+}
+
+// Anything without flagNoNewline is written (?s:.), only the case folding is left
+func (re2Syntax) modifier(flags matchFlag, t term) term {
+	if flags&flagFold == 0 {
+		return t
+	}
+	return term{"(?i:" + t.src + ")", termAtom}
+}
+
+func (syntax re2Syntax) state(s *state) (term, error) {
+	switch s.Tag {
+	case epsilon, end:
+		return term{}, nil
+	case anything:
+		if s.flags&flagNoNewline != 0 {
+			return term{".", termAtom}, nil
+		}
+		return term{"(?s:.)", termAtom}, nil
+
+	case text:
+		// The empty text still fails at the end of the input
+		if s.str == "" {
+			return term{}, errorTranslate("the empty text <''>")
+		}
+		src, err := re2Text(s.str)
+		if utf8.RuneCountInString(s.str) == 1 {
+			return term{src, termAtom}, err
+		}
+		return term{src, termSeq}, err
+
+	case set, scope:
+		ranges, ok := s.charRanges()
+		if s.Tag == scope {
+			ranges, ok = append([][2]rune{}, s.ranges...), true
+		}
+		if !ok {
+			return term{}, errorTranslate("the empty set")
+		}
+		return re2Class(mergeRanges(ranges), s.neg, s.runes)
+
+	case class:
+		if src, found := re2Classes[s.str]; found {
+			return term{src, termAtom}, nil
+		}
+
+	case not:
+		// Only the negation of a single character has an RE2 form
+		seq := s.seq.Clone().Simplify()
+		if len(seq.edges) == 0 {
+			if src, found := re2Classes[seq.state.str]; found && seq.state.Tag == class {
+				return term{`\P` + src[2:], termAtom}, nil
+			}
+			if ranges, ok := seq.state.charRanges(); ok {
+				t, err := re2Class(mergeRanges(ranges), true, s.runes)
+				return syntax.modifier(seq.state.flags, t), err
+			}
+		}
+		return term{}, errorTranslate("the negation of a sequence <!>")
+
+	case anchor:
+		return term{re2Anchors[s.str], termAtom}, nil
+
+	case dash:
+		return term{}, errorTranslate("the lookahead <%c>", '/')
+	case behind:
+		return term{}, errorTranslate("the lookbehind <%c>", '\\')
+	}
+
+	return term{}, errorTranslate("state %d", s.Tag)
+}
+
+func re2Text(str string) (string, error) {
+	var sb strings.Builder
+	for i := 0; i < len(str); {
+		c, size := utf8.DecodeRuneInString(str[i:])
+		if c == utf8.RuneError && size == 1 {
+			return "", errorTranslate("the byte \\x%02x outside UTF-8", str[i])
+		}
+		sb.WriteString(re2Rune(c, false))
+		i += size
+	}
+	return sb.String(), nil
+}
+
+func re2Rune(c rune, class bool) string {
+	switch {
+	case c == '\n':
+		return `\n`
+	case c == '\t':
+		return `\t`
+	case c == '\r':
+		return `\r`
+	case c == '\f':
+		return `\f`
+	case c == '\v':
+		return `\v`
+	case !unicode.IsPrint(c) || c == ' ' && class:
+		return fmt.Sprintf(`\x{%x}`, c)
+	case class && strings.ContainsRune(`\[]^-`, c):
+		return `\` + string(c)
+	case class:
+		return string(c)
+	default:
+		return regexp.QuoteMeta(string(c))
+	}
+}
+
+func re2Class(ranges [][2]rune, neg bool, runes bool) (term, error) {
+	if !runes {
+		for _, r := range ranges {
+			if r[1] >= utf8.RuneSelf {
+				return term{}, errorTranslate("the bytes %#x-%#x outside ASCII", r[0], r[1])
+			}
+		}
+	}
+	if !neg && len(ranges) == 1 && ranges[0][0] == ranges[0][1] {
+		return term{re2Rune(ranges[0][0], false), termAtom}, nil
+	}
+
+	var sb strings.Builder
+	sb.WriteByte('[')
+	if neg {
+		sb.WriteByte('^')
+	}
+	for _, r := range ranges {
+		sb.WriteString(re2Rune(r[0], true))
+		if r[0] != r[1] {
+			sb.WriteByte('-')
+			sb.WriteString(re2Rune(r[1], true))
+		}
+	}
+	sb.WriteByte(']')
+	return term{sb.String(), termAtom}, nil
+}
+
+// Bee regex source of the RE2 source, as RE2 output it may begin with ^. Non-ASCII
+// characters of the classes are only matched in ModeRunes
+func FromRE2(src string) (string, error) {
+	re, err := syntax.Parse(src, syntax.Perl)
+	if err != nil {
+		return "", err
+	}
+	t, err := fromRE2(dropBeginText(re))
+	return t.src, err
+}
+
+// Bee regexes match at the beginning of the input, the leading ^ of re or of its
+// alternatives is dropped
+func dropBeginText(re *syntax.Regexp) *syntax.Regexp {
+	switch {
+	case re.Op == syntax.OpBeginText:
+		return &syntax.Regexp{Op: syntax.OpEmptyMatch}
+	case re.Op == syntax.OpConcat && re.Sub[0].Op == syntax.OpBeginText:
+		re.Sub = re.Sub[1:]
+	case re.Op == syntax.OpAlternate:
+		for i, sub := range re.Sub {
+			re.Sub[i] = dropBeginText(sub)
+		}
+	}
+	return re
+}
+
+func fromRE2(re *syntax.Regexp) (term, error) {
+	var bee beeSyntax
+
+	switch re.Op {
+	case syntax.OpLiteral:
+		if re.Flags&syntax.FoldCase != 0 {
+			return bee.modifier(flagFold, term{printText(string(re.Rune)), termAtom}), nil
+		}
+		return term{printText(string(re.Rune)), termAtom}, nil
+
+	case syntax.OpCharClass:
+		ranges := make([][2]rune, 0, len(re.Rune)/2)
+		for i := 0; i+1 < len(re.Rune); i += 2 {
+			ranges = append(ranges, [2]rune{re.Rune[i], re.Rune[i+1]})
+		}
+		if len(ranges) == 0 {
+			return term{}, errorTranslate("the empty class <%s>", re)
+		}
+		for tok, table := range classTables {
+			if equalRanges(ranges, tableRanges(table)) {
+				return term{string(tok), termAtom}, nil
+			}
+		}
+		if ranges[0][0] == 0 && ranges[len(ranges)-1][1] == unicode.MaxRune {
+			if len(ranges) == 1 {
+				return term{"^", termAtom}, nil
+			}
+			complement := make([][2]rune, 0, len(ranges)-1)
+			for i := 1; i < len(ranges); i++ {
+				complement = append(complement, [2]rune{ranges[i-1][1] + 1, ranges[i][0] - 1})
+			}
+			return term{printScope(complement, true, true), termAtom}, nil
+		}
+		return term{printScope(ranges, false, true), termAtom}, nil
+
+	case syntax.OpBeginLine:
+		return term{string(anchorLineBegin), termAtom}, nil
+	case syntax.OpEndLine:
+		return term{string(anchorLineEnd), termAtom}, nil
+	case syntax.OpEndText:
+		return term{string(anchorInputEnd), termAtom}, nil
+	case syntax.OpWordBoundary:
+		return term{string(anchorWord), termAtom}, nil
+
+	case syntax.OpAnyCharNotNL:
+		return bee.modifier(flagNoNewline, term{"^", termAtom}), nil
+	case syntax.OpAnyChar:
+		return term{"^", termAtom}, nil
+
+	case syntax.OpCapture:
+		inner, err := fromRE2(re.Sub[0])
+		return bee.group(re.Name, inner), err
+
+	case syntax.OpStar, syntax.OpPlus, syntax.OpQuest, syntax.OpRepeat:
+		lazy := ""
+		if re.Flags&syntax.NonGreedy != 0 {
+			lazy = "?"
+		}
+		if lazy != "" && re.Op == syntax.OpRepeat {
+			return term{}, errorTranslate("the lazy repetition <%s>", re)
+		}
+		operand, err := fromRE2(re.Sub[0])
+		if err != nil {
+			return term{}, err
+		}
+		switch {
+		case re.Op == syntax.OpStar:
+			return bee.post(operand, "*"+lazy), nil
+		case re.Op == syntax.OpPlus:
+			return bee.post(operand, "+"+lazy), nil
+		case re.Op == syntax.OpQuest:
+			return bee.post(operand, "?"+lazy), nil
+		case re.Max == -1:
+			return bee.post(operand, fmt.Sprintf("#%d,", re.Min)), nil
+		case re.Min == re.Max:
+			return bee.post(operand, fmt.Sprintf("#%d", re.Min)), nil
+		default:
+			return bee.post(operand, fmt.Sprintf("#%d,%d", re.Min, re.Max)), nil
+		}
+
+	case syntax.OpConcat:
+		items := make([]term, 0, len(re.Sub))
+		for _, sub := range re.Sub {
+			item, err := fromRE2(sub)
+			if err != nil {
+				return term{}, err
+			}
+			items = append(items, item)
+		}
+		return bee.seq(items), nil
+
+	case syntax.OpEmptyMatch:
+		return term{"''", termAtom}, nil
+
+	// The prefixes factored by the parser leave empty alternatives such as a(?:|b) for
+	// a|ab, an empty first alternative makes the others lazy and a last one optional
+	case syntax.OpAlternate:
+		subs := re.Sub
+		lazy := len(subs) > 1 && subs[0].Op == syntax.OpEmptyMatch
+		if lazy {
+			subs = subs[1:]
+		}
+		optional := len(subs) > 1 && subs[len(subs)-1].Op == syntax.OpEmptyMatch
+		if optional {
+			subs = subs[:len(subs)-1]
+		}
+		alts := make([]term, 0, len(subs))
+		for _, sub := range subs {
+			alt, err := fromRE2(sub)
+			if err != nil {
+				return term{}, err
+			}
+			alts = append(alts, alt)
+		}
+		switch {
+		case lazy:
+			return bee.post(bee.alt(alts), "??"), nil
+		case optional:
+			return bee.post(bee.alt(alts), "?"), nil
+		}
+		return bee.alt(alts), nil
+	}
+
+	return term{}, errorTranslate("<%s>", re)
+}
+
+func tableRanges(table *unicode.RangeTable) [][2]rune {
+	ranges := make([][2]rune, 0, len(table.R16)+len(table.R32))
+	for _, r := range table.R16 {
+		for c := rune(r.Lo); c <= rune(r.Hi); c += rune(r.Stride) {
+			ranges = append(ranges, [2]rune{c, c})
+		}
+	}
+	for _, r := range table.R32 {
+		for c := rune(r.Lo); c <= rune(r.Hi); c += rune(r.Stride) {
+			ranges = append(ranges, [2]rune{c, c})
+		}
+	}
+	return mergeRanges(ranges)
+}
+
+func equalRanges(a [][2]rune, b [][2]rune) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -3,8 +3,12 @@ package main
 import (
 	"fmt"
 	"io"
+	"math"
 	"sort"
+	"strconv"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 )
 
 func min(a, b int) int {
@@ -15,10 +19,39 @@ func min(a, b int) int {
 	}
 }
 
+// Limit of the #n,m repetition counts and of the nodes a repetition expands to
+const maxRepeat = 1000
+
 type stateTag uint
 type nodes []*node
 type stack []*node
 
+type RegexMode uint
+
+const (
+	// Table-driven DFA, patterns that cannot be determinized use ModeLinear
+	ModeDfa RegexMode = 0
+	// Recursive node.Submit, exponential on nested repetitions
+	ModeBacktrack RegexMode = 1 << 0
+	// Memoized (node, index) simulation in O(len(pattern)·len(input))
+	ModeLinear RegexMode = 1 << 1
+	// Anything, set, scope and not states advance by UTF-8 decoded runes
+	ModeRunes RegexMode = 1 << 2
+	// Leftmost-longest match instead of the first successful path, without a DFA the
+	// node graph is simulated with the set of reachable nodes at each index
+	ModeLongest RegexMode = 1 << 3
+)
+
+// Characters of the set tokens
+const (
+	setBlank  = " \n\v\b\f\t"
+	setAlpha  = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+	setOp     = "!#$%&()*+,-./:;<=>?@[\\]^`{|}~"
+	setNum    = "0123456789"
+	setQuote  = "\""
+	setSQuote = "'"
+)
+
 const (
 	epsilon stateTag = iota
 	anything
@@ -28,51 +61,339 @@ const (
 	text
 	set
 	scope
+	class
+	begin
+	end
+	anchor
+	behind
+)
+
+// Tokens of the anchors, <:name> is a fragment reference and not anchors
+const (
+	anchorLineBegin = '<'
+	anchorLineEnd   = '>'
+	anchorInputEnd  = '$'
+	anchorWord      = '%'
+)
+
+// Modifiers of a sequence applying to the states parsed inside it
+type matchFlag uint
+
+const (
+	// Texts, sets and scopes ignore the case, ASCII letters unless the state matches runes
+	flagFold matchFlag = 1 << iota
+	// Anything does not match newlines
+	flagNoNewline
 )
 
 type state struct {
-	Tag stateTag
-	str string
-	seq *node
-	a   byte
-	b   byte
+	Tag    stateTag
+	str    string
+	seq    *node
+	ranges [][2]rune
+	neg    bool
+	group  int
+	table  *unicode.RangeTable
+	runes  bool
+	flags  matchFlag
 }
 
 type node struct {
 	state state
 	index int
 	edges nodes
+	// Repetition copies holding the node, outermost first
+	repeats []*repeatCopy
+}
+
+// Copy of the operand of a #n,m repetition, the copies after the nth are optional
+type repeatCopy struct {
+	index    int
+	count    string
+	optional bool
 }
 
+type memoKey struct {
+	n     *node
+	index int
+}
+
+type memo map[memoKey]int
+
 type parser struct {
-	sr    *strings.Reader
-	stack stack
+	src    string
+	sr     *strings.Reader
+	stack  stack
+	groups *[]string
+	mode   RegexMode
+	flags  matchFlag
+	// Fragments referenced as <:name>, the name of the fragment being parsed if any
+	fragments *Fragments
+	fragment  string
+	// Token closing a nested sequence or group opened at the offset, 0 for the whole pattern
+	close  byte
+	open   int
+	closed bool
+}
+
+// Regex compile error located at the byte offset of the operator involved in the pattern
+type RegexError struct {
+	Src    string
+	Offset int
+	Op     byte
+	Desc   string
 }
 
 type Regex struct {
 	Src  string
 	Head *node
+	Mode RegexMode
+	// Capture group names by index, positional groups are unnamed and 0 is the whole match
+	Groups []string
+	dfa    *dfa
 }
 
 type RegexGraph struct {
 	sb   strings.Builder
 	head *node
 	Name string
+	// Nodes and edges taken by a traced match, a nil source is the entry edge
+	path  map[*node]bool
+	edges map[[2]*node]bool
+	fail  *[2]*node
 }
 
 func NewRegex(src string) (Regex, error) {
+	return NewRegexMode(src, ModeDfa)
+}
+
+func NewRegexMode(src string, mode RegexMode) (Regex, error) {
+	return NewRegexFragments(src, mode, nil)
+}
+
+// Regex whose <:name> references are replaced by a copy of the fragment graphs
+func NewRegexFragments(src string, mode RegexMode, fragments *Fragments) (Regex, error) {
 	p := newRegexParser(src)
+	p.mode = mode
+	p.fragments = fragments
 	head, err := p.Parse()
 
 	if err != nil {
 		return Regex{}, err
 	}
 
-	return Regex{Src: src, Head: head}, nil
+	rx := Regex{Src: src, Head: head, Mode: mode, Groups: *p.groups}
+	rx.compile()
+	return rx, nil
+}
+
+// Builds the DFA of the graph when the mode allows it
+func (rx *Regex) compile() {
+	rx.dfa = nil
+	if rx.Head != nil && rx.Mode&(ModeBacktrack|ModeLinear) == 0 {
+		if pg, ok := compileProg(rx.Head); ok {
+			rx.dfa, _ = newDfa(pg, rx.Mode&ModeLongest != 0)
+		}
+	}
 }
 
 func (rx *Regex) Match(expr string) int {
-	return rx.Head.Submit(expr, 0)
+	return rx.MatchAt(expr, 0)
+}
+
+// Length of the match starting at the index of src, -1 without a match. Anchors and
+// lookbehinds see the characters of src before the index
+func (rx *Regex) MatchAt(src string, index int) int {
+	match := -1
+	switch {
+	case rx.Mode&(ModeBacktrack|ModeLinear) == 0 && rx.dfa != nil:
+		// Graphs with anchors or lookbehinds are not determinized
+		return rx.dfa.Match(src[index:])
+	case rx.Mode&ModeLongest != 0:
+		match = rx.Head.SubmitLongest(src, index)
+	case rx.Mode&ModeBacktrack != 0:
+		match = rx.Head.Submit(src, index)
+	default:
+		match = make(memo).Submit(rx.Head, src, index)
+	}
+
+	if match == -1 {
+		return -1
+	}
+	return match - index
+}
+
+// Length of the match at the index of src where src may be followed by more input
+// unless eof. More tells that the match depends on the input past src, it has to be
+// tried again once more input is appended
+func (rx *Regex) MatchMore(src string, index int, eof bool) (int, bool) {
+	if eof {
+		return rx.MatchAt(src, index), false
+	}
+
+	match, more := -1, false
+	switch {
+	case rx.Mode&(ModeBacktrack|ModeLinear) == 0 && rx.dfa != nil:
+		return rx.dfa.MatchMore(src[index:], false)
+	case rx.Mode&ModeLongest != 0:
+		match, more = rx.Head.submitLongest(src, index)
+	default:
+		// The memo takes the same path as the backtracking of node.Submit
+		m := make(memo)
+		match = m.Submit(rx.Head, src, index)
+		more = m.pastEnd(src)
+	}
+
+	if match == -1 {
+		return -1, more
+	}
+	return match - index, more
+}
+
+// First match anywhere in expr as a [begin, end) interval, -1, -1 without a match
+func (rx *Regex) Find(expr string) (int, int) {
+	return rx.findFrom(expr, 0)
+}
+
+func (rx *Regex) findFrom(expr string, index int) (int, int) {
+	for begin := index; begin <= len(expr); begin = rx.advance(expr, begin) {
+		if match := rx.MatchAt(expr, begin); match != -1 {
+			return begin, begin + match
+		}
+	}
+	return -1, -1
+}
+
+// Successive non-overlapping matches in expr, at most n of them unless n is negative.
+// Empty matches right after a previous match are skipped
+func (rx *Regex) FindAll(expr string, n int) [][2]int {
+	spans := make([][2]int, 0)
+	prev := -1
+
+	for begin := 0; begin <= len(expr) && (n < 0 || len(spans) < n); {
+		b, e := rx.findFrom(expr, begin)
+		if b == -1 {
+			break
+		}
+
+		if b == e {
+			begin = rx.advance(expr, e)
+			if b == prev {
+				continue
+			}
+		} else {
+			begin = e
+		}
+		spans = append(spans, [2]int{b, e})
+		prev = e
+	}
+
+	return spans
+}
+
+// Copy of expr where every match is replaced by repl, without any expansion
+func (rx *Regex) Replace(expr string, repl string) string {
+	return rx.ReplaceFunc(expr, func(string) string {
+		return repl
+	})
+}
+
+// Copy of expr where every match is replaced by the result of f on the matched string
+func (rx *Regex) ReplaceFunc(expr string, f func(string) string) string {
+	var sb strings.Builder
+	last := 0
+
+	for _, span := range rx.FindAll(expr, -1) {
+		sb.WriteString(expr[last:span[0]])
+		sb.WriteString(f(expr[span[0]:span[1]]))
+		last = span[1]
+	}
+	sb.WriteString(expr[last:])
+
+	return sb.String()
+}
+
+// Next search position, a whole rune ahead in ModeRunes
+func (rx *Regex) advance(expr string, index int) int {
+	if rx.Mode&ModeRunes != 0 && index < len(expr) {
+		_, size := utf8.DecodeRuneInString(expr[index:])
+		return index + size
+	}
+	return index + 1
+}
+
+// Spans of each capture group in the matched prefix of expr, or nil without a match.
+// Groups not taking part in the match have a {-1, -1} span. In ModeLongest the groups
+// come from the first path ending at the longest match
+func (rx *Regex) MatchGroups(expr string) [][2]int {
+	m := make(memo)
+	path := rx.path(expr, m)
+	if path == nil {
+		return nil
+	}
+
+	spans := make([][2]int, len(rx.Groups))
+	for i := range spans {
+		spans[i] = [2]int{-1, -1}
+	}
+	for _, step := range path {
+		switch step.n.state.Tag {
+		case begin:
+			spans[step.n.state.group][0] = step.index
+		case end:
+			spans[step.n.state.group][1] = step.index
+		}
+	}
+
+	last := path[len(path)-1]
+	spans[0] = [2]int{0, last.n.state.submit(expr, last.index, m.Submit)}
+	return spans
+}
+
+// (node, index) pairs along the matching path of expr, or nil without a match. The
+// memo tells which edge node.Submit takes
+func (rx *Regex) path(expr string, m memo) []memoKey {
+	viable := func(n *node, index int) bool {
+		return m.Submit(n, expr, index) != -1
+	}
+	if rx.Mode&ModeLongest != 0 {
+		viable = m.Reaches(expr, rx.Match(expr))
+	}
+	if rx.Head == nil || !viable(rx.Head, 0) {
+		return nil
+	}
+
+	path := make([]memoKey, 0, 16)
+	visited := make(map[memoKey]bool)
+	n, index := rx.Head, 0
+	for n != nil {
+		path = append(path, memoKey{n, index})
+		visited[memoKey{n, index}] = true
+		match := n.state.submit(expr, index, m.Submit)
+
+		next := (*node)(nil)
+		if n.Branch() || match < len(expr) {
+			for _, edge := range n.edges {
+				if !visited[memoKey{edge, match}] && viable(edge, match) {
+					next = edge
+					break
+				}
+			}
+		}
+		n, index = next, match
+	}
+
+	return path
+}
+
+// Index of the capture group with the given name, -1 if there is none
+func (rx *Regex) GroupIndex(name string) int {
+	for i, group := range rx.Groups {
+		if i != 0 && group == name {
+			return i
+		}
+	}
+	return -1
 }
 
 func (rx *Regex) Graph(name string) string {
@@ -80,6 +401,13 @@ func (rx *Regex) Graph(name string) string {
 	return graph.Document()
 }
 
+// Graph coloring the path taken when matching expr and the failing edge without a match
+func (rx *Regex) TraceGraph(name string, expr string) string {
+	trace := rx.Trace(expr)
+	graph := NewRegexTraceGraph(rx, name, &trace)
+	return graph.Document()
+}
+
 func (s nodes) Len() int {
 	return len(s)
 }
@@ -134,8 +462,28 @@ func newSet(str string) state {
 	return state{Tag: set, str: str}
 }
 
-func newScope(a byte, b byte) state {
-	return state{Tag: scope, a: a, b: b}
+func newScope(ranges [][2]rune, neg bool) state {
+	return state{Tag: scope, ranges: ranges, neg: neg}
+}
+
+func newClass(name string, table *unicode.RangeTable) state {
+	return state{Tag: class, str: name, table: table}
+}
+
+func newAnchor(tok byte) state {
+	return state{Tag: anchor, str: string(tok)}
+}
+
+func newBehind(node *node) state {
+	return state{Tag: behind, seq: node}
+}
+
+func newBegin(group int, name string) state {
+	return state{Tag: begin, group: group, str: name}
+}
+
+func newEnd(group int, name string) state {
+	return state{Tag: end, group: group, str: name}
 }
 
 func newNode(state state) *node {
@@ -146,51 +494,275 @@ func newNode(state state) *node {
 	}
 }
 
+func newQuest(pre *node) *node {
+	quest := newNode(newEpsilon())
+	quest.Push(pre)
+	quest.Push(newNode(newEpsilon()))
+	return quest
+}
+
+func newStar(pre *node) *node {
+	star := newNode(newEpsilon())
+	star.Merge(pre)
+	star.Concat(star)
+	star.Push(newNode(newEpsilon()))
+	return star
+}
+
+// Edges are tried by index, the exit is pushed before the operand to be tried first
+
+func newLazyQuest(pre *node) *node {
+	quest := newNode(newEpsilon())
+	quest.Push(newNode(newEpsilon()))
+	quest.Push(pre)
+	return quest
+}
+
+func newLazyStar(pre *node) *node {
+	star := newNode(newEpsilon())
+	star.Push(newNode(newEpsilon()))
+	star.Push(pre)
+	pre.Concat(star)
+	return star
+}
+
 func (s *state) Submit(expr string, index int) int {
-	if s.Tag != epsilon && index >= len(expr) {
-		return -1
-	}
+	return s.submit(expr, index, (*node).Submit)
+}
 
+func (s *state) submit(expr string, index int, submit func(*node, string, int) int) int {
 	switch s.Tag {
-	case epsilon:
+	case epsilon, begin, end:
 		return index
 
+	case anchor:
+		if s.Anchors(expr, index) {
+			return index
+		}
+		return -1
+
+	case text:
+		// The empty text matches at the end of expr too
+		if s.str == "" {
+			return index
+		}
+
+	case behind:
+		reach := reaches(expr, index, submit)
+		for from := index; from >= 0 && from >= index-s.seq.MaxWidth(); from-- {
+			if reach(s.seq, from) {
+				return index
+			}
+		}
+		return -1
+	}
+
+	if index >= len(expr) {
+		return -1
+	}
+
+	c, size := s.decode(expr, index)
+
+	switch s.Tag {
 	case anything:
-		return index + 1
+		if c != '\n' || s.flags&flagNoNewline == 0 {
+			return index + size
+		}
 
 	case not:
-		if match := s.seq.Submit(expr, index); match == -1 {
-			return index + 1
+		if match := submit(s.seq, expr, index); match == -1 {
+			return index + size
 		}
 
 	case dash:
-		if match := s.seq.Submit(expr, index); match != -1 {
+		if match := submit(s.seq, expr, index); match != -1 {
 			return index
 		}
 
 	case text:
-		length := min(len(s.str), len(expr)-index)
-
-		if s.str[:length] == expr[index:index+length] {
-			return index + length
+		if strings.HasPrefix(expr[index:], s.str) {
+			return index + len(s.str)
+		}
+		if s.flags&flagFold != 0 {
+			return s.submitFold(expr, index)
 		}
 
 	case set:
-		if strings.IndexByte(s.str, expr[index]) != -1 {
-			return index + 1
+		if s.accepts(c, func(c rune) bool { return strings.ContainsRune(s.str, c) }) {
+			return index + size
 		}
 
 	case scope:
-		if s.a <= expr[index] && expr[index] <= s.b {
-			return index + 1
+		if s.accepts(c, s.Contains) != s.neg {
+			return index + size
+		}
+
+	case class:
+		if unicode.Is(s.table, c) {
+			return index + size
 		}
 	}
 
 	return -1
 }
 
+func (s *state) Contains(c rune) bool {
+	for _, r := range s.ranges {
+		if r[0] <= c && c <= r[1] {
+			return true
+		}
+	}
+	return false
+}
+
+// Whether the anchor holds at the index of expr. Word characters are the ASCII letters,
+// digits and <_>, as for the a, n and '_' sets
+func (s *state) Anchors(expr string, index int) bool {
+	switch s.str[0] {
+	case anchorLineBegin:
+		return index == 0 || expr[index-1] == '\n'
+	case anchorLineEnd:
+		return index == len(expr) || expr[index] == '\n'
+	case anchorInputEnd:
+		return index == len(expr)
+	case anchorWord:
+		return isWord(expr, index-1) != isWord(expr, index)
+	}
+	return false
+}
+
+func isWord(expr string, index int) bool {
+	if index < 0 || index >= len(expr) {
+		return false
+	}
+	c := expr[index]
+	return c == '_' || strings.IndexByte(setAlpha, c) != -1 || strings.IndexByte(setNum, c) != -1
+}
+
+// Upper bound of the bytes consumed from the node, math.MaxInt when it has loops
+func (n *node) MaxWidth() int {
+	widths := make(map[*node]int)
+
+	var width func(n *node) int
+	width = func(n *node) int {
+		if w, found := widths[n]; found {
+			return w
+		}
+		widths[n] = math.MaxInt
+		w := n.state.maxWidth()
+		next := 0
+		for _, edge := range n.edges {
+			if edge.index <= n.index {
+				next = math.MaxInt
+				break
+			}
+			if e := width(edge); e > next {
+				next = e
+			}
+		}
+		if w == math.MaxInt || next == math.MaxInt {
+			w = math.MaxInt
+		} else {
+			w += next
+		}
+		widths[n] = w
+		return w
+	}
+
+	return width(n)
+}
+
+// Upper bound of the bytes before the index read by the anchors and lookbehinds of a
+// match from the node, math.MaxInt when a lookbehind has loops
+func (n *node) BehindWidth() int {
+	width := 0
+	for _, memb := range n.Membs() {
+		s, w := &memb.state, 0
+		switch {
+		case s.Tag == anchor && (s.str == string(anchorLineBegin) || s.str == string(anchorWord)):
+			w = 1
+		case s.Tag == behind:
+			w = s.seq.MaxWidth()
+			if seq := s.seq.BehindWidth(); w == math.MaxInt || seq == math.MaxInt {
+				w = math.MaxInt
+			} else {
+				w += seq
+			}
+		case s.seq != nil:
+			w = s.seq.BehindWidth()
+		}
+		if w > width {
+			width = w
+		}
+	}
+	return width
+}
+
+func (s *state) maxWidth() int {
+	switch s.Tag {
+	case epsilon, none, begin, end, anchor, behind, dash:
+		return 0
+	case text:
+		if s.runes && s.flags&flagFold != 0 {
+			return utf8.UTFMax * utf8.RuneCountInString(s.str)
+		}
+		return len(s.str)
+	case anything, not, set, scope, class:
+		if s.runes || s.Tag == class {
+			return utf8.UTFMax
+		}
+		return 1
+	}
+	return math.MaxInt
+}
+
+// Whether c or one of its case variants with flagFold passes the test
+func (s *state) accepts(c rune, test func(rune) bool) bool {
+	if test(c) {
+		return true
+	}
+	if s.flags&flagFold == 0 || (!s.runes && c >= utf8.RuneSelf) {
+		return false
+	}
+	for f := unicode.SimpleFold(c); f != c; f = unicode.SimpleFold(f) {
+		if (s.runes || f < utf8.RuneSelf) && test(f) {
+			return true
+		}
+	}
+	return false
+}
+
+// Match of the text ignoring the case, character by character as the case variants
+// of a rune may have another width
+func (s *state) submitFold(expr string, index int) int {
+	for i := 0; i < len(s.str); {
+		if index >= len(expr) {
+			return -1
+		}
+		a, sizeA := s.decode(s.str, i)
+		b, sizeB := s.decode(expr, index)
+		if !s.accepts(b, func(c rune) bool { return c == a }) {
+			return -1
+		}
+		i, index = i+sizeA, index+sizeB
+	}
+	return index
+}
+
+// Character at the index with its width, a single byte unless the state matches runes
+func (s *state) decode(expr string, index int) (rune, int) {
+	if s.runes || s.Tag == class {
+		return utf8.DecodeRuneInString(expr[index:])
+	}
+	return rune(expr[index]), 1
+}
+
 func (n *node) Submit(expr string, index int) int {
-	match := n.state.Submit(expr, index)
+	return n.submit(expr, index, (*node).Submit)
+}
+
+func (n *node) submit(expr string, index int, submit func(*node, string, int) int) int {
+	match := n.state.submit(expr, index, submit)
 
 	if match != -1 {
 		branch := n.Branch()
@@ -199,7 +771,7 @@ func (n *node) Submit(expr string, index int) int {
 		}
 
 		for _, edge := range n.edges {
-			matchFwd := edge.Submit(expr, match)
+			matchFwd := submit(edge, expr, match)
 			if matchFwd != -1 {
 				return matchFwd
 			}
@@ -213,12 +785,130 @@ func (n *node) Submit(expr string, index int) int {
 	return -1
 }
 
-func (n *node) makeMembers(membs *nodes) *nodes {
+// The outcome of node.Submit only depends on the (node, index) pair, each pair is
+// submitted once. A pair submitted again while in progress is an epsilon cycle and fails
+func (m memo) Submit(n *node, expr string, index int) int {
+	key := memoKey{n, index}
+	if match, found := m[key]; found {
+		return match
+	}
+
+	m[key] = -1
+	match := n.submit(expr, index, m.Submit)
+	m[key] = match
+	return match
+}
+
+// Whether a submitted pair depends on the input past expr
+func (m memo) pastEnd(expr string) bool {
+	for key, match := range m {
+		if match >= len(expr) || key.pastEnd(expr) {
+			return true
+		}
+	}
+	return false
+}
+
+// Whether the state of the node submitted at the index may read past expr: at its end, on
+// a text cut by it or on a rune cut by it
+func (key memoKey) pastEnd(expr string) bool {
+	if key.index >= len(expr) {
+		return true
+	}
+	s, rest := &key.n.state, expr[key.index:]
+	switch {
+	case s.Tag == text && len(rest) < len(s.str):
+		return s.flags&flagFold != 0 || strings.HasPrefix(s.str, rest)
+	case s.runes || s.Tag == class:
+		return !utf8.FullRuneInString(rest)
+	}
+	return false
+}
+
+// Whether a path from a node at an index accepts exactly at the end index
+func (m memo) Reaches(expr string, end int) func(*node, int) bool {
+	return reaches(expr, end, m.Submit)
+}
+
+func reaches(expr string, end int, submit func(*node, string, int) int) func(*node, int) bool {
+	reached := make(map[memoKey]bool)
+
+	var reaches func(*node, int) bool
+	reaches = func(n *node, index int) bool {
+		key := memoKey{n, index}
+		if ok, found := reached[key]; found {
+			return ok
+		}
+
+		reached[key] = false
+		match := n.state.submit(expr, index, submit)
+		ok := match != -1 && !n.Branch() && match == end
+		for i := 0; match != -1 && !ok && i < len(n.edges); i++ {
+			ok = reaches(n.edges[i], match)
+		}
+		reached[key] = ok
+		return ok
+	}
+
+	return reaches
+}
+
+// Longest match by walking the nodes reachable at each index, every (node, index)
+// pair is entered once
+func (n *node) SubmitLongest(expr string, index int) int {
+	longest, _ := n.submitLongest(expr, index)
+	return longest
+}
+
+// The longest match and whether it depends on the input past expr
+func (n *node) submitLongest(expr string, index int) (int, bool) {
+	longest, more := -1, false
+	seqs := make(memo)
+	entered := make(map[memoKey]bool)
+	pending := map[int]nodes{index: {n}}
+
+	for i := index; i <= len(expr) && len(pending) != 0; i++ {
+		queue := pending[i]
+		delete(pending, i)
+
+		for len(queue) != 0 {
+			cur := queue[len(queue)-1]
+			queue = queue[:len(queue)-1]
+			if entered[memoKey{cur, i}] {
+				continue
+			}
+			entered[memoKey{cur, i}] = true
+			more = more || memoKey{cur, i}.pastEnd(expr)
+
+			match := cur.state.submit(expr, i, seqs.Submit)
+			if match == -1 {
+				continue
+			}
+			if !cur.Branch() && match > longest {
+				longest = match
+			}
+			if match == i {
+				queue = append(queue, cur.edges...)
+			} else {
+				pending[match] = append(pending[match], cur.edges...)
+			}
+		}
+	}
+
+	return longest, more || longest >= len(expr) || seqs.pastEnd(expr)
+}
+
+// Nodes reached by the forward edges, each once even when branches join
+func (n *node) makeMembers(membs *nodes, seen map[*node]bool) *nodes {
+	if seen[n] {
+		return membs
+	}
+	seen[n] = true
 	*membs = append(*membs, n)
 
 	for _, edge := range n.edges {
 		if edge.index > n.index {
-			edge.makeMembers(membs)
+			edge.makeMembers(membs, seen)
 		}
 	}
 
@@ -227,7 +917,7 @@ func (n *node) makeMembers(membs *nodes) *nodes {
 
 func (n *node) Membs() nodes {
 	membs := make(nodes, 0, 32)
-	return *n.makeMembers(&membs)
+	return *n.makeMembers(&membs, make(map[*node]bool))
 }
 
 func (n *node) NextIndex() int {
@@ -260,6 +950,34 @@ func (n *node) Concat(edge *node) *node {
 	return edge
 }
 
+// Deep copy of the graph, including the sequences of not and dash states
+func (n *node) Clone() *node {
+	clones := make(map[*node]*node)
+	membs := n.Membs()
+
+	for _, member := range membs {
+		if clones[member] == nil {
+			clone := newNode(member.state)
+			clone.index = member.index
+			clone.repeats = member.repeats
+			if member.state.seq != nil {
+				clone.state.seq = member.state.seq.Clone()
+			}
+			clones[member] = clone
+		}
+	}
+	for member, clone := range clones {
+		for _, edge := range member.edges {
+			if clones[edge] != nil {
+				edge = clones[edge]
+			}
+			clone.edges = append(clone.edges, edge)
+		}
+	}
+
+	return clones[n]
+}
+
 func (n *node) Scope(base int) {
 	for _, member := range n.Membs() {
 		member.index += base
@@ -287,11 +1005,61 @@ func (s stack) Pop() (stack, *node) {
 }
 
 func newRegexParser(src string) parser {
-	return parser{sr: strings.NewReader(src)}
+	return parser{src: src, sr: strings.NewReader(src), groups: &[]string{""}}
+}
+
+// Parser sharing the reader until the close token of a nested sequence or group
+func (p *parser) subParser(close byte) parser {
+	return parser{
+		src:       p.src,
+		sr:        p.sr,
+		groups:    p.groups,
+		mode:      p.mode,
+		flags:     p.flags,
+		fragments: p.fragments,
+		fragment:  p.fragment,
+		close:     close,
+		open:      p.offset() - 1,
+	}
+}
+
+func (p *parser) offset() int {
+	return int(p.sr.Size()) - p.sr.Len()
+}
+
+// Node matching runes in ModeRunes, with the modifiers of the sequence
+func (p *parser) newNode(state state) *node {
+	state.runes = p.mode&ModeRunes != 0
+	switch state.Tag {
+	case text, set, scope:
+		state.flags = p.flags & flagFold
+	case anything:
+		state.flags = p.flags & flagNoNewline
+	}
+	return newNode(state)
+}
+
+func (p *parser) readChar() (rune, error) {
+	if p.mode&ModeRunes != 0 {
+		c, _, err := p.sr.ReadRune()
+		return c, err
+	}
+	c, err := p.sr.ReadByte()
+	return rune(c), err
 }
 
 func (p *parser) Parse() (*node, error) {
-	for p.sr.Len() != 0 {
+	for !p.closed {
+		if p.sr.Len() == 0 {
+			switch p.close {
+			case '}':
+				return nil, p.errorAt(p.open, '{', "Unmatched sequence brace, missing <}> token")
+			case ')':
+				return nil, p.errorAt(p.open, '(', "Unmatched group parenthesis, missing <)> token")
+			}
+			break
+		}
+
 		seq, err := p.nextToken()
 		if err != nil {
 			return nil, err
@@ -318,28 +1086,43 @@ func (p *parser) nextToken() (*node, error) {
 	if err != nil {
 		return nil, err
 	}
+	at := p.offset() - 1
 
 	switch tok {
 	case ' ', '\f', '\n', '\r', '\t', '\v':
 		return p.nextToken()
 
 	case '_':
-		return p.parseSet(" \n\v\b\f\t")
+		return p.parseSet(setBlank)
 	case 'a':
-		return p.parseSet("ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz")
+		return p.parseSet(setAlpha)
 	case 'o':
-		return p.parseSet("!#$%&()*+,-./:;<=>?@[\\]^`{|}~")
+		return p.parseSet(setOp)
 	case 'n':
-		return p.parseSet("0123456789")
+		return p.parseSet(setNum)
 	case 'Q':
-		return p.parseSet("\"")
+		return p.parseSet(setQuote)
 	case 'q':
-		return p.parseSet("'")
+		return p.parseSet(setSQuote)
+	case 'A':
+		return p.parseClass("Letter", unicode.Letter)
+	case 'D':
+		return p.parseClass("Digit", unicode.Digit)
 
 	case '!':
 		return p.parseNot()
 	case '/':
 		return p.parseDash()
+	case '\\':
+		return p.parseBehind()
+	case anchorLineBegin:
+		// <:name> is a fragment reference, <name> stays anchors around a name
+		if name := p.readFragmentName(); name != "" {
+			return p.parseFragment(at, name)
+		}
+		return p.newNode(newAnchor(tok)), nil
+	case anchorLineEnd, anchorInputEnd, anchorWord:
+		return p.newNode(newAnchor(tok)), nil
 
 	case '[':
 		return p.parseScope()
@@ -351,6 +1134,8 @@ func (p *parser) nextToken() (*node, error) {
 		return p.parseText('`')
 	case '{':
 		return p.parseSequence()
+	case '(':
+		return p.parseGroup()
 	case '|':
 		return p.parseOr()
 	case '?':
@@ -361,13 +1146,22 @@ func (p *parser) nextToken() (*node, error) {
 		return p.parseStar()
 	case '~':
 		return p.parseWave()
+	case '#':
+		return p.parseRepeat()
 
-	case '}':
-		return nil, p.errorf("Unmatched sequence brace, missing <{> operator")
+	case '}', ')':
+		if tok == p.close {
+			p.closed = true
+			return nil, nil
+		}
+		if tok == '}' {
+			return nil, p.errorAt(at, tok, "Unmatched sequence brace, missing <{> operator")
+		}
+		return nil, p.errorAt(at, tok, "Unmatched group parenthesis, missing <(> operator")
 	case ']':
-		return nil, p.errorf("Unmatched scope brace, missing <[> operator")
+		return nil, p.errorAt(at, tok, "Unmatched scope brace, missing <[> operator")
 	default:
-		return nil, p.errorf("'%c': Unrecognized token in regex, none of [_aonQq^'{}!|?*+~]", tok)
+		return nil, p.errorAt(at, tok, "'%c': Unrecognized token in regex, none of [_aonQqAD^'{}()!/\\<>$%%|?*+~#]", tok)
 	}
 }
 
@@ -375,19 +1169,20 @@ func (p *parser) parsePreOp(op byte) (*node, error) {
 	var seq *node
 
 	if p.stack, seq = p.stack.Pop(); seq == nil {
-		return seq, p.errorf("Missing pre-operand here for <%c>", op)
+		return seq, p.errorAt(p.offset()-1, op, "Missing pre-operand here for <%c>", op)
 	}
 
 	return seq, nil
 }
 
 func (p *parser) parsePostOp(op byte) (*node, error) {
+	at := p.offset() - 1
 	seq, err := p.nextToken()
 	if err != nil {
 		return nil, err
 	}
 	if seq == nil {
-		return nil, p.errorf("Missing post-operand here for <%c>", op)
+		return nil, p.errorAt(at, op, "Missing post-operand here for <%c>", op)
 	}
 	return seq, err
 }
@@ -409,7 +1204,11 @@ func (p *parser) parseBinOp(op byte) (*node, *node, error) {
 }
 
 func (p *parser) parseSet(str string) (*node, error) {
-	return newNode(newSet(str)), nil
+	return p.newNode(newSet(str)), nil
+}
+
+func (p *parser) parseClass(name string, table *unicode.RangeTable) (*node, error) {
+	return p.newNode(newClass(name, table)), nil
 }
 
 // NOTE: Cannot because of initialization cycle error, may come up with a solution later
@@ -427,89 +1226,268 @@ func (p *parser) parseSet(str string) (*node, error) {
 // 	return newNode(newScope(buf[0], buf[2])), nil
 // }
 
-// sad way
+// [^ a b-c ... ], a leading <]> is a character and the upper boundary of an interval
+// is always read as a character
 func (p *parser) parseScope() (*node, error) {
-	// [ ^ - ^ ]
-	//-1 0 1 2 3
-	a, _ := p.sr.ReadByte()
-	if hyphen, _ := p.sr.ReadByte(); hyphen != '-' {
-		return nil, p.errorf("Expected <-> token between scope boundaries")
-	}
-	b, _ := p.sr.ReadByte()
-	if end, _ := p.sr.ReadByte(); end != ']' {
-		return nil, p.errorf("Expected <]> token at the end of the scope declaration")
-	}
+	open := p.offset() - 1
+	ranges := make([][2]rune, 0, 4)
+	neg := p.readIf('^')
 
-	if a > b {
-		return nil, p.errorf(`Scope is not matchable because the interval of characters is empty`)
+	for {
+		at := p.offset()
+		a, err := p.readChar()
+		if err != nil {
+			return nil, p.errorAt(open, '[', "Unmatched scope brace, missing <]> token")
+		}
+		if a == ']' && len(ranges) != 0 {
+			break
+		}
+
+		b := a
+		if p.readIf('-') {
+			if b, err = p.readChar(); err != nil {
+				return nil, p.errorAt(open, '[', "Unmatched scope brace, missing <]> token")
+			}
+		}
+		if a > b {
+			return nil, p.errorAt(at, '-', "'%c-%c': Scope is not matchable because the interval of characters is empty", a, b)
+		}
+		ranges = append(ranges, [2]rune{a, b})
 	}
 
-	return newNode(newScope(a, b)), nil
+	return p.newNode(newScope(ranges, neg)), nil
+}
+
+// Consumes the next byte when it is c
+func (p *parser) readIf(c byte) bool {
+	next, err := p.sr.ReadByte()
+	if err == nil && next != c {
+		p.sr.UnreadByte()
+	}
+	return err == nil && next == c
 }
 
 func (p *parser) parseAnything() (*node, error) {
-	return newNode(newAnything()), nil
+	return p.newNode(newAnything()), nil
 }
 
 func (p *parser) parseText(stop byte) (*node, error) {
-	readText := func(buf *[]byte) error {
-		for {
-			c, err := p.sr.ReadByte()
-			switch {
-			case err != nil:
-				return err
-			case c == stop:
-				return nil
-			default:
-				*buf = append(*buf, c)
+	open := p.offset() - 1
+	buf := make([]byte, 0)
+
+	for {
+		c, err := p.sr.ReadByte()
+		switch {
+		case err != nil:
+			return nil, p.errorAt(open, stop, "Unmatched text quote, missing <%c> token", stop)
+		case c == stop:
+			return p.newNode(newText(string(buf))), nil
+		case c == '\\':
+			if buf, err = p.readEscape(buf); err != nil {
+				return nil, err
 			}
+		default:
+			buf = append(buf, c)
 		}
+	}
+}
 
+// Appends the character of the escape sequence following a backslash in a text
+func (p *parser) readEscape(buf []byte) ([]byte, error) {
+	at := p.offset() - 1
+	c, err := p.sr.ReadByte()
+	if err != nil {
+		return nil, p.errorAt(at, '\\', "Unterminated escape sequence")
 	}
 
-	buf := make([]byte, 0)
-	if err := readText(&buf); err != nil {
-		return nil, err
+	switch c {
+	case '0':
+		return append(buf, 0), nil
+	case 'f':
+		return append(buf, '\f'), nil
+	case 'n':
+		return append(buf, '\n'), nil
+	case 'r':
+		return append(buf, '\r'), nil
+	case 't':
+		return append(buf, '\t'), nil
+	case 'v':
+		return append(buf, '\v'), nil
+	case '\\', '\'', '`':
+		return append(buf, c), nil
+
+	case 'x':
+		digits := make([]byte, 2)
+		if n, _ := p.sr.Read(digits); n == 2 {
+			if x, err := strconv.ParseUint(string(digits), 16, 8); err == nil {
+				return append(buf, byte(x)), nil
+			}
+		}
+		return nil, p.errorAt(at, '\\', "Expected two hexadecimal digits in <\\x> escape sequence")
+
+	case 'u':
+		if p.readIf('{') {
+			digits := make([]byte, 0, 6)
+			for len(digits) <= 6 {
+				d, err := p.sr.ReadByte()
+				if err != nil {
+					break
+				}
+				if d != '}' {
+					digits = append(digits, d)
+					continue
+				}
+				x, err := strconv.ParseUint(string(digits), 16, 32)
+				if err == nil && utf8.ValidRune(rune(x)) {
+					return utf8.AppendRune(buf, rune(x)), nil
+				}
+				return nil, p.errorAt(at, '\\', "'%s': Invalid code point in <\\u> escape sequence", digits)
+			}
+		}
+		return nil, p.errorAt(at, '\\', "Expected <\\u{...}> escape sequence with at most 6 hexadecimal digits")
+
+	default:
+		return nil, p.errorAt(at, '\\', "'\\%c': Unknown escape sequence in text", c)
 	}
-	str := string(buf)
-	return newNode(newText(str)), nil
 }
 
-func (p *parser) readSequenceSrc() (string, error) {
-	depth := 1
-	buf := make([]byte, 0)
+func (p *parser) parseSequence() (*node, error) {
+	sp := p.subParser('}')
+	if p.readIf('?') {
+		flags, err := p.readModifiers(p.flags)
+		if err != nil {
+			return nil, err
+		}
+		sp.flags = flags
+	}
+	return sp.Parse()
+}
+
+// Reads the modifiers of a {?i-s: ...} sequence: <i> ignores the case of texts, sets and
+// scopes, <s> lets <^> match newlines, which it does by default. Modifiers after <-> are
+// turned off
+func (p *parser) readModifiers(flags matchFlag) (matchFlag, error) {
+	at := p.offset() - 1
+	off := false
 
 	for {
-		tok, err := p.sr.ReadByte()
-		if err != nil {
-			return "", p.errorf("Unmatched sequence brace, missing <}> token")
+		c, err := p.sr.ReadByte()
+		switch {
+		case err != nil:
+			return 0, p.errorAt(at, '?', "Unterminated modifiers, missing <:> token")
+		case c == ':':
+			return flags, nil
+		case c == '-' && !off:
+			off = true
+		case c == 'i' && off, c == 's' && !off:
+			flags &^= modifierFlag(c)
+		case c == 'i', c == 's':
+			flags |= modifierFlag(c)
+		default:
+			return 0, p.errorAt(p.offset()-1, c, "'%c': Unknown modifier, none of [is-]", c)
 		}
+	}
+}
 
-		switch tok {
-		case '{':
-			depth++
-		case '}':
-			depth--
+func modifierFlag(c byte) matchFlag {
+	if c == 'i' {
+		return flagFold
+	}
+	return flagNoNewline
+}
+
+// Source form of the modifiers, as in {?i-s: ...}
+func (f matchFlag) String() string {
+	var on, off string
+	if f&flagFold != 0 {
+		on += "i"
+	}
+	if f&flagNoNewline != 0 {
+		off += "s"
+	}
+	if off != "" {
+		return on + "-" + off
+	}
+	return on
+}
+
+// Reads the <name:> prefix of a named group, nothing is consumed for positional groups
+func (p *parser) readGroupName() string {
+	return p.readName(':')
+}
+
+// Reads the <:name>> rest of a fragment reference, nothing is consumed for anchors
+func (p *parser) readFragmentName() string {
+	offset := p.offset()
+	if p.readIf(':') {
+		if name := p.readName('>'); name != "" {
+			return name
 		}
+	}
+	p.sr.Seek(int64(offset), io.SeekStart)
+	return ""
+}
 
-		if depth < 1 {
-			break
-		} else {
-			buf = append(buf, tok)
+// Reads a name made of letters, digits and <_> up to the stop token, nothing is consumed
+// when there is no such name
+func (p *parser) readName(stop byte) string {
+	offset := p.offset()
+	name := make([]byte, 0)
+
+	for {
+		c, err := p.sr.ReadByte()
+		switch {
+		case err != nil:
+		case c == stop && len(name) != 0:
+			return string(name)
+		case c == '_' || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z'):
+			name = append(name, c)
+			continue
+		case '0' <= c && c <= '9' && len(name) != 0:
+			name = append(name, c)
+			continue
 		}
+		break
 	}
 
-	return string(buf), nil
+	p.sr.Seek(int64(offset), io.SeekStart)
+	return ""
 }
 
-func (p *parser) parseSequence() (*node, error) {
-	src, err := p.readSequenceSrc()
+func (p *parser) parseFragment(at int, name string) (*node, error) {
+	if p.fragments == nil {
+		return nil, p.errorAt(at, '<', "'%s': Unknown fragment, no fragments are defined", name)
+	}
+	return p.fragments.graph(p, at, name)
+}
+
+func (p *parser) parseGroup() (*node, error) {
+	at := p.offset()
+	if p.fragment != "" {
+		return nil, p.errorAt(at-1, '(', "'%s': Capture groups are not allowed in fragments", p.fragment)
+	}
+	name := p.readGroupName()
+	for _, group := range *p.groups {
+		if name != "" && group == name {
+			return nil, p.errorAt(at, '(', "'%s': Capture group name is already defined", name)
+		}
+	}
+	*p.groups = append(*p.groups, name)
+	group := len(*p.groups) - 1
+
+	sp := p.subParser(')')
+	seq, err := sp.Parse()
 	if err != nil {
 		return nil, err
 	}
 
-	sp := newRegexParser(src)
-	return sp.Parse()
+	begin := newNode(newBegin(group, name))
+	if seq != nil {
+		begin.Merge(seq)
+	}
+	begin.Merge(newNode(newEnd(group, name)))
+
+	return begin, nil
 }
 
 func (p *parser) parseDash() (*node, error) {
@@ -521,13 +1499,23 @@ func (p *parser) parseDash() (*node, error) {
 	return newNode(newDash(seq)), err
 }
 
+// Lookbehind, the sequence ends exactly at the index without consuming anything
+func (p *parser) parseBehind() (*node, error) {
+	seq, err := p.parsePostOp('\\')
+	if err != nil {
+		return nil, err
+	}
+
+	return newNode(newBehind(seq)), err
+}
+
 func (p *parser) parseNot() (*node, error) {
 	seq, err := p.parsePostOp('!')
 	if err != nil {
 		return nil, err
 	}
 
-	return newNode(newNot(seq)), err
+	return p.newNode(newNot(seq)), err
 }
 
 func (p *parser) parseOr() (*node, error) {
@@ -543,17 +1531,18 @@ func (p *parser) parseOr() (*node, error) {
 	return or, err
 }
 
+// The quantifiers followed by <?> are lazy, trying to leave before repeating the operand
+
 func (p *parser) parseQuest() (*node, error) {
 	pre, err := p.parsePreOp('?')
 	if err != nil {
 		return nil, err
 	}
 
-	quest := newNode(newEpsilon())
-	quest.Push(pre)
-	quest.Push(newNode(newEpsilon()))
-
-	return quest, err
+	if p.readIf('?') {
+		return newLazyQuest(pre), err
+	}
+	return newQuest(pre), err
 }
 
 func (p *parser) parseStar() (*node, error) {
@@ -562,12 +1551,10 @@ func (p *parser) parseStar() (*node, error) {
 		return nil, err
 	}
 
-	star := newNode(newEpsilon())
-	star.Merge(pre)
-	star.Concat(star)
-	star.Push(newNode(newEpsilon()))
-
-	return star, err
+	if p.readIf('?') {
+		return newLazyStar(pre), err
+	}
+	return newStar(pre), err
 }
 
 func (p *parser) parsePlus() (*node, error) {
@@ -576,10 +1563,112 @@ func (p *parser) parsePlus() (*node, error) {
 		return nil, err
 	}
 
+	// The back edge of the leaves is always tried first, a lazy plus is {X X*?}
+	if p.readIf('?') {
+		plus.Merge(newLazyStar(plus.Clone()))
+		return plus, err
+	}
 	plus.Concat(plus)
 	return plus, err
 }
 
+// Operand repeated between n and m times with #n,m, #n is exactly n times and #n, is
+// at least n times. The operand graph is copied for each repetition
+func (p *parser) parseRepeat() (*node, error) {
+	pre, err := p.parsePreOp('#')
+	if err != nil {
+		return nil, err
+	}
+
+	at := p.offset() - 1
+	n, ok := p.readCount()
+	if !ok {
+		return nil, p.errorAt(at, '#', "Expected repetition count after <#> operator")
+	}
+	m := n
+	if p.readIf(',') {
+		if m, ok = p.readCount(); !ok {
+			m = -1
+		}
+	}
+	if m != -1 && m < n {
+		return nil, p.errorAt(at, '#', "Repetition is not matchable because #%d,%d is an empty interval", n, m)
+	}
+	if n > maxRepeat || m > maxRepeat {
+		return nil, p.errorAt(at, '#', "Repetition count is above the #%d limit", maxRepeat)
+	}
+	count := n
+	switch {
+	case m == -1:
+		count = n + 1
+	case m > n:
+		count = m
+	case n == 0:
+		count = 1
+	}
+	if size := count * len(pre.Membs()); size > maxRepeat {
+		return nil, p.errorAt(at, '#', "Repetition expands to %d nodes, above the limit of %d", size, maxRepeat)
+	}
+
+	copies := make(nodes, 0, count)
+	copies = append(copies, pre)
+	for i := 1; i < count; i++ {
+		copies = append(copies, pre.Clone())
+	}
+	spec := p.src[at:p.offset()]
+	for i, c := range copies {
+		rc := &repeatCopy{index: i + 1, count: spec, optional: i >= n}
+		for _, member := range c.Membs() {
+			member.repeats = append([]*repeatCopy{rc}, member.repeats...)
+		}
+	}
+
+	var tail *node
+	switch {
+	case m == -1:
+		tail = newStar(copies[n])
+	case m > n:
+		for i := m - 1; i >= n; i-- {
+			if tail != nil {
+				copies[i].Merge(tail)
+			}
+			tail = newQuest(copies[i])
+		}
+	}
+
+	if n == 0 {
+		if tail == nil {
+			return newNode(newEpsilon()), nil
+		}
+		return tail, nil
+	}
+	for i := 1; i < n; i++ {
+		copies[0].Merge(copies[i])
+	}
+	if tail != nil {
+		copies[0].Merge(tail)
+	}
+	return copies[0], nil
+}
+
+func (p *parser) readCount() (int, bool) {
+	digits := make([]byte, 0, 4)
+	for {
+		c, err := p.sr.ReadByte()
+		if err != nil {
+			break
+		}
+		if c < '0' || '9' < c {
+			p.sr.UnreadByte()
+			break
+		}
+		digits = append(digits, c)
+	}
+
+	count, err := strconv.Atoi(string(digits))
+	return count, err == nil
+}
+
 func (p *parser) parseWave() (*node, error) {
 	pre, post, err := p.parseBinOp('~')
 	if err != nil {
@@ -595,8 +1684,31 @@ func (p *parser) parseWave() (*node, error) {
 	return wave, err
 }
 
-func (p *parser) errorf(desc string, args ...interface{}) error {
-	return fmt.Errorf(desc, args...)
+func (p *parser) errorAt(offset int, op byte, desc string, args ...interface{}) error {
+	return &RegexError{
+		Src:    p.src,
+		Offset: offset,
+		Op:     op,
+		Desc:   fmt.Sprintf(desc, args...),
+	}
+}
+
+//	Example: regex > '0x' [0-9a-f
+//	                      ^ Unmatched scope brace, missing <]> token
+
+func (e *RegexError) Error() string {
+	begin := strings.LastIndexByte(e.Src[:e.Offset], '\n') + 1
+	end := len(e.Src)
+	if newLine := strings.IndexByte(e.Src[e.Offset:], '\n'); newLine != -1 {
+		end = e.Offset + newLine
+	}
+
+	// The caret counts runes past the trimmed indentation, as Parser.errorf
+	location := "regex > "
+	line := e.Src[begin:end]
+	snippet := strings.TrimLeft(line, " \t\v\f\r")
+	cursor := len(location) + utf8.RuneCountInString(e.Src[begin:e.Offset]) + 1 - utf8.RuneCountInString(line[:len(line)-len(snippet)])
+	return fmt.Sprintf("%s%s\n%*c %s", location, snippet, cursor, '^', e.Desc)
 }
 
 func NewRegexGraph(rx *Regex, name string) RegexGraph {
@@ -607,6 +1719,34 @@ func NewRegexGraph(rx *Regex, name string) RegexGraph {
 	}
 }
 
+func NewRegexTraceGraph(rx *Regex, name string, trace *RegexTrace) RegexGraph {
+	rg := NewRegexGraph(rx, name)
+	rg.path = make(map[*node]bool)
+	rg.edges = make(map[[2]*node]bool)
+	if rx.Head == nil {
+		return rg
+	}
+
+	numbered := rx.Head.Numbered()
+	from := (*node)(nil)
+	for _, id := range trace.Path {
+		n := numbered[trace.Steps[id].Node]
+		rg.path[n] = true
+		rg.edges[[2]*node{from, n}] = true
+		from = n
+	}
+
+	if trace.Fail != -1 {
+		step := trace.Steps[trace.Fail]
+		fail := [2]*node{nil, numbered[step.Node]}
+		if step.Parent != -1 {
+			fail[0] = numbered[trace.Steps[step.Parent].Node]
+		}
+		rg.fail = &fail
+	}
+	return rg
+}
+
 func (rg *RegexGraph) write(f string, args ...interface{}) {
 	rg.sb.WriteString(fmt.Sprintf(f, args...))
 }
@@ -623,9 +1763,11 @@ func (rg *RegexGraph) Document() string {
 	if rg.head != nil {
 		rg.writeln(`rankdir=LR;bgcolor="#F9F9F9";compound=true`)
 		rg.writeln(`"%s" [shape="none"]`, rg.Name)
-		rg.writeln(`"%s" -> "%p" [label="%s"]`, rg.Name, rg.head, rg.makeState(rg.head))
+		rg.writeln(`"%s" -> "%p" [label="%s"%s]`, rg.Name, rg.head, rg.makeState(rg.head), rg.edgeStyle(nil, rg.head))
 
-		for _, member := range rg.head.Membs() {
+		membs := rg.head.Membs()
+		rg.formatRepeats(membs, 0)
+		for _, member := range membs {
 			rg.format(member)
 		}
 	}
@@ -636,16 +1778,43 @@ func (rg *RegexGraph) Document() string {
 
 func (rg *RegexGraph) define(n *node) {
 	shape := "?"
-	if n.Branch() {
+	switch {
+	case n.state.Tag == begin:
+		shape = "rarrow"
+	case n.state.Tag == end:
+		shape = "larrow"
+	case n.Branch():
 		shape = "square"
-	} else {
+	default:
 		shape = "circle"
 	}
-	rg.writeln(`"%p" [shape="%s", label="%d"]`, n, shape, n.index)
+	style := ""
+	switch {
+	case rg.fail != nil && rg.fail[1] == n:
+		style = `, style="filled", fillcolor="#F6C9C9"`
+	case rg.path[n]:
+		style = `, style="filled", fillcolor="#CDEFC4"`
+	}
+	rg.writeln(`"%p" [shape="%s", label="%d"%s]`, n, shape, n.index, style)
 }
 
 func (rg *RegexGraph) connect(a *node, b *node) {
-	rg.writeln(`"%p" -> "%p" [label="%s"]`, a, b, rg.makeState(b))
+	rg.connectFrom(a, a, b)
+}
+
+// Edge drawn from a but taken from the from node, differing for the sequence of a subgraph
+func (rg *RegexGraph) connectFrom(a *node, from *node, b *node) {
+	rg.writeln(`"%p" -> "%p" [label="%s"%s]`, a, b, rg.makeState(b), rg.edgeStyle(from, b))
+}
+
+func (rg *RegexGraph) edgeStyle(a *node, b *node) string {
+	switch {
+	case rg.fail != nil && *rg.fail == [2]*node{a, b}:
+		return `, color="#C62828", penwidth=2`
+	case rg.edges[[2]*node{a, b}]:
+		return `, color="#2E7D32", penwidth=2`
+	}
+	return ""
 }
 
 func (rg *RegexGraph) formatSubgraph(n *node, header string) {
@@ -664,7 +1833,46 @@ func (rg *RegexGraph) formatSubgraph(n *node, header string) {
 
 	rg.writeln(`}`)
 	for _, edge := range n.edges {
-		rg.connect(max, edge)
+		rg.connectFrom(max, n, edge)
+	}
+}
+
+// Clusters of the repetition copies, labeled with their index and count and dashed when
+// optional. Nested repetitions are nested clusters, the nodes are defined by format
+func (rg *RegexGraph) formatRepeats(membs nodes, depth int) {
+	copies := make([]*repeatCopy, 0, 4)
+	groups := make(map[*repeatCopy]nodes)
+	for _, member := range membs {
+		if len(member.repeats) <= depth {
+			continue
+		}
+		rc := member.repeats[depth]
+		if groups[rc] == nil {
+			copies = append(copies, rc)
+		}
+		groups[rc] = append(groups[rc], member)
+	}
+
+	for _, rc := range copies {
+		group := groups[rc]
+		rg.writeln(`subgraph cluster_repeat%d_%p {`, depth, group[0])
+		style := ""
+		if rc.optional {
+			style = `;style=dashed`
+		}
+		rg.writeln(`label="%d of %s";labeljust=l%s`, rc.index, rc.count, style)
+		rg.formatRepeats(group, depth+1)
+		for _, member := range group {
+			// Not, dash and behind states are drawn in their own cluster
+			switch member.state.Tag {
+			case not, dash, behind:
+			default:
+				if len(member.repeats) == depth+1 {
+					rg.writeln(`"%p"`, member)
+				}
+			}
+		}
+		rg.writeln(`}`)
 	}
 }
 
@@ -676,6 +1884,8 @@ func (rg *RegexGraph) format(n *node) {
 		rg.formatSubgraph(n, `style=filled;bgcolor="#FBF3F3"`)
 	case dash:
 		rg.formatSubgraph(n, `style=filled;bgcolor="#F4FDFF"`)
+	case behind:
+		rg.formatSubgraph(n, `style=filled;bgcolor="#FFFBEF"`)
 	default:
 		rg.define(n)
 		for _, edge := range n.edges {
@@ -685,8 +1895,14 @@ func (rg *RegexGraph) format(n *node) {
 }
 
 func (rg *RegexGraph) makeState(n *node) string {
-	s := n.state
+	label := rg.makeLabel(n.state)
+	if n.state.flags != 0 {
+		return fmt.Sprintf("{?%s: %s}", n.state.flags, label)
+	}
+	return label
+}
 
+func (rg *RegexGraph) makeLabel(s state) string {
 	switch s.Tag {
 	case epsilon:
 		return "&Sigma;"
@@ -698,6 +1914,10 @@ func (rg *RegexGraph) makeState(n *node) string {
 		return "!"
 	case dash:
 		return "/"
+	case behind:
+		return `\\`
+	case anchor:
+		return s.str
 
 	case text:
 		return fmt.Sprintf("'%s'", s.str)
@@ -713,7 +1933,33 @@ func (rg *RegexGraph) makeState(n *node) string {
 		}
 
 	case scope:
-		return fmt.Sprintf("[%c-%c]", s.a, s.b)
+		var sb strings.Builder
+		sb.WriteByte('[')
+		if s.neg {
+			sb.WriteByte('^')
+		}
+		for _, r := range s.ranges {
+			if r[0] == r[1] {
+				sb.WriteRune(r[0])
+			} else {
+				sb.WriteString(fmt.Sprintf("%c-%c", r[0], r[1]))
+			}
+		}
+		sb.WriteByte(']')
+		return sb.String()
+	case class:
+		return fmt.Sprintf("[:%s:]", s.str)
+
+	case begin:
+		if s.str != "" {
+			return fmt.Sprintf("(%s:", s.str)
+		}
+		return fmt.Sprintf("(%d", s.group)
+	case end:
+		if s.str != "" {
+			return fmt.Sprintf("%s)", s.str)
+		}
+		return fmt.Sprintf("%d)", s.group)
 	}
 
 	return "?"
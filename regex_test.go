@@ -1,8 +1,14 @@
 package main
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
 	"testing"
+	"unicode/utf8"
 )
 
 const LoremIpsum = `Lorem ipsum dolor sit amet, consectetur adipiscing elit, sed do eiusmod tempor incididunt ut
@@ -30,6 +36,16 @@ func expectRegex(ts *testing.T, src string, expr string, crashExpected bool, mat
 	regex.Graph("test")
 
 	match := regex.Match(expr)
+	if regex.Head != nil {
+		if backtrack := regex.Head.Submit(expr, 0); backtrack != match {
+			ts.Logf(`"%s" matched %d of "%s" but backtracking matched %d`, src, match, expr, backtrack)
+			ts.Fail()
+		}
+		if linear := make(memo).Submit(regex.Head, expr, 0); linear != match {
+			ts.Logf(`"%s" matched %d of "%s" but linear matched %d`, src, match, expr, linear)
+			ts.Fail()
+		}
+	}
 	if matchExpected && match == -1 {
 		ts.Logf(`"%s" doesn't matched "%s"`, src, expr)
 		ts.Fail()
@@ -60,6 +76,20 @@ func expectError(ts *testing.T, src string) {
 	expectRegex(ts, src, "", true, false, -1)
 }
 
+func expectErrorAt(ts *testing.T, src string, offset int, op byte) {
+	_, err := NewRegex(src)
+	rxErr, ok := err.(*RegexError)
+	if !ok {
+		ts.Logf(`"%s" expected a regex error at %d, got %v`, src, offset, err)
+		ts.Fail()
+		return
+	}
+	if rxErr.Offset != offset || rxErr.Op != op {
+		ts.Logf(`"%s" error at %d on <%c> instead of %d on <%c>: %s`, src, rxErr.Offset, rxErr.Op, offset, op, rxErr.Desc)
+		ts.Fail()
+	}
+}
+
 func TestRegexUnknown(ts *testing.T) {
 	expectError(ts, "N")
 	expectError(ts, ")")
@@ -77,6 +107,8 @@ func TestRegexText(ts *testing.T) {
 	expectMatch(ts, "`abc`", "abcccccccccc")
 	expectMatch(ts, "`hello` ` ` `world`", "hello world")
 	expectMatch(ts, "`hello\nworld`", "hello\nworld")
+	expectMatchEq(ts, "''", "", 0)
+	expectMatchEq(ts, "'a' ''", "a", 1)
 
 	expectError(ts, "`hello'")
 	expectError(ts, "'hello`")
@@ -88,10 +120,43 @@ func TestRegexText(ts *testing.T) {
 
 	expectNoMatch(ts, "'cba'", "abc")
 	expectNoMatch(ts, "'cbaa'", "abcc")
+	expectNoMatch(ts, "'abc'", "ab")
+	expectNoMatch(ts, "'->'", "-")
 	expectNoMatch(ts, fmt.Sprintf("`%s`", LoremIpsum), LoremIpsum[1:])
 	expectNoMatch(ts, fmt.Sprintf("`%s`", LoremIpsum), LoremIpsum[2:len(LoremIpsum)-2])
 }
 
+func TestRegexEscape(ts *testing.T) {
+	expectMatchEq(ts, `'\n'`, "\n", 1)
+	expectMatchEq(ts, `'a\tb'`, "a\tb", 3)
+	expectMatchEq(ts, `'\r\n'`, "\r\n", 2)
+	expectMatchEq(ts, `'\0'`, "\x00", 1)
+	expectMatchEq(ts, `'\\'`, `\`, 1)
+	expectMatchEq(ts, `'\''`, `'`, 1)
+	expectMatchEq(ts, "`\\``", "`", 1)
+	expectMatchEq(ts, "'\\'' '`'", "'`", 2)
+	expectMatchEq(ts, "`'\\``", "'`", 2)
+	expectMatchEq(ts, `'\x41\x62'`, "Ab", 2)
+	expectMatchEq(ts, `'\u{41}'`, "A", 1)
+	expectMatchEq(ts, `'\u{1F4A1}'`, "💡", 4)
+	expectMatchEq(ts, `'\u{e9}t\u{e9}'`, "été", 5)
+	expectMatchEq(ts, `{{'\\' ^} | !'\''}+`, `a\'b'`, 4)
+
+	expectNoMatch(ts, `'\n'`, "n")
+	expectNoMatch(ts, `'\\n'`, "\n")
+
+	expectError(ts, `'\'`)
+	expectError(ts, `'\q'`)
+	expectError(ts, `'\x4'`)
+	expectError(ts, `'\xzz'`)
+	expectError(ts, `'\u41'`)
+	expectError(ts, `'\u{}'`)
+	expectError(ts, `'\u{110000}'`)
+	expectError(ts, `'\u{D800}'`)
+	expectError(ts, `'\u{1234567}'`)
+	expectError(ts, `'abc\`)
+}
+
 func TestRegexRange(ts *testing.T) {
 	expectMatchEq(ts, "[0-9]+", "0123456789yeet", 10)
 	expectMatchEq(ts, "[a-f]+", "abcdefghijklmnopqrstuvwxyz", 6)
@@ -112,6 +177,33 @@ func TestRegexRange(ts *testing.T) {
 	expectError(ts, "0-9]")
 }
 
+func TestRegexClass(ts *testing.T) {
+	expectMatchEq(ts, "[0-9a-fA-F]+", "0xff", 1)
+	expectMatchEq(ts, "'0x' [0-9a-fA-F]+", "0xC0ffee;", 8)
+	expectMatchEq(ts, "[abc]+", "cabbage", 5)
+	expectMatchEq(ts, "[-+]?[0-9]+", "-42", 3)
+	expectMatchEq(ts, "[a-c-]+", "a-b-c-d", 6)
+	expectMatchEq(ts, "[]]+", "]]]", 3)
+	expectMatchEq(ts, "{[}]}+", "}}", 2)
+	expectMatchEq(ts, "([)])", ")", 1)
+
+	expectMatchEq(ts, "[^0-9]+", "abc123", 3)
+	expectMatchEq(ts, "[^ \t\n]+", "word next", 4)
+	expectMatchEq(ts, "q [^'\n]* q", "'text' more", 6)
+	expectMatchEq(ts, "[^^]+", "ab^", 2)
+	expectNoMatch(ts, "[^a-z]", "q")
+	expectNoMatch(ts, "[^a]", "")
+
+	expectModeEq(ts, ModeRunes, "[α-ωa-z]+", "αbγ!", 5)
+	expectModeEq(ts, ModeRunes, "[^a-z]+", "ÀÉa", 4)
+
+	expectError(ts, "[]")
+	expectError(ts, "[^")
+	expectError(ts, "[a-]")
+	expectError(ts, "[z-a]")
+	expectError(ts, "[0-9a-f")
+}
+
 func TestRegexSet(ts *testing.T) {
 	expectMatch(ts, "_", "\n")
 	expectMatch(ts, "a", "a")
@@ -177,6 +269,31 @@ func TestRegexQuest(ts *testing.T) {
 	expectError(ts, "{}?")
 }
 
+func TestRegexLazy(ts *testing.T) {
+	expectMatchEq(ts, "'/*' ^* '*/'", "/* a */ b */", 12)
+	expectMatchEq(ts, "'/*' ^*? '*/'", "/* a */ b */", 7)
+	expectMatchEq(ts, "a+", "abc", 3)
+	expectMatchEq(ts, "a+?", "abc", 1)
+	expectMatchEq(ts, "a*?", "abc", 0)
+	expectMatchEq(ts, "'a'?", "abc", 1)
+	expectMatchEq(ts, "'a'??", "abc", 0)
+	expectMatchEq(ts, "'a'?? 'ab'", "abc", 2)
+	expectMatchEq(ts, "{'a'|'b'}*? 'b'", "aabb", 3)
+	expectMatchEq(ts, "(x: n+?) n*", "123", 3)
+	expectError(ts, "*?")
+
+	// The longest match ignores laziness
+	expectModeEq(ts, ModeLongest, "'/*' ^*? '*/'", "/* a */ b */", 12)
+	expectModeEq(ts, ModeLinear, "'/*' ^*? '*/'", "/* a */ b */", 7)
+	expectModeEq(ts, ModeBacktrack, "a+?", "aaa", 1)
+
+	rx, _ := NewRegex("(x: n+?) n*")
+	if groups := rx.MatchGroups("123"); !reflect.DeepEqual(groups, [][2]int{{0, 3}, {0, 1}}) {
+		ts.Logf("Lazy group spans %v", groups)
+		ts.Fail()
+	}
+}
+
 func TestRegexOr(ts *testing.T) {
 	expectMatch(ts, "{'a'|'b'}", "a")
 	expectMatch(ts, "{'a'|'b'}", "a")
@@ -193,6 +310,56 @@ func TestRegexOr(ts *testing.T) {
 	expectError(ts, "|'b'")
 }
 
+func TestRegexRepeat(ts *testing.T) {
+	expectMatchEq(ts, "n#3", "12345", 3)
+	expectMatchEq(ts, "n#2,4", "123456", 4)
+	expectMatchEq(ts, "n#2,4", "12a", 2)
+	expectMatchEq(ts, "n#2,", "123456a", 6)
+	expectMatchEq(ts, "n#0", "1", 0)
+	expectMatchEq(ts, "n#0,1", "12", 1)
+	expectMatchEq(ts, "n#0,", "123", 3)
+	expectMatchEq(ts, "{'ab'}#2", "ababab", 4)
+	expectMatchEq(ts, "{'a'|'b'}#2,3 'c'", "abac", 4)
+	expectMatchEq(ts, "{n+ ','}#2", "1,23,456,", 5)
+	expectNoMatch(ts, "{!_}#3", "ab c")
+	expectMatchEq(ts, `'\\u' [0-9a-fA-F]#4`, `\u00e9x`, 6)
+	expectMatchEq(ts, "(n#1,2)#3", "1234567", 6)
+
+	expectNoMatch(ts, "n#3", "12")
+	expectNoMatch(ts, "n#2,", "1")
+
+	regex, _ := NewRegex("(n)#3")
+	if spans := regex.MatchGroups("123"); spans == nil || spans[1] != [2]int{2, 3} {
+		ts.Logf(`"%s" captured %v`, regex.Src, spans)
+		ts.Fail()
+	}
+
+	expectError(ts, "#3")
+	expectError(ts, "n#")
+	expectError(ts, "n#a")
+	expectError(ts, "n#,3")
+	expectError(ts, "n#4,2")
+	expectError(ts, "n#100000")
+	expectError(ts, "{a|n}#400")
+	expectError(ts, "{n#40}#40")
+
+	// Joining branches are expanded once per copy
+	regex, _ = NewRegex("{a|n}#100")
+	if _, err := regex.Canonical(); regex.Match("a1") != -1 || err != nil {
+		ts.Logf(`"%s" failed on the expanded branches: %v`, regex.Src, err)
+		ts.Fail()
+	}
+
+	regex, _ = NewRegex("{n#2}#1,2")
+	graph := regex.Graph("test")
+	for _, label := range []string{`label="1 of #2"`, `label="2 of #2"`, `label="1 of #1,2"`, `label="2 of #1,2";labeljust=l;style=dashed`} {
+		if strings.Count(graph, label) == 0 {
+			ts.Logf("Repetition copy %s is missing from the graph:\n%s", label, graph)
+			ts.Fail()
+		}
+	}
+}
+
 func TestRegexWave(ts *testing.T) {
 	expectMatch(ts, "^~'c'", "abc")
 	expectMatch(ts, "a~'z'", "ahjklz")
@@ -207,3 +374,884 @@ func TestRegexWave(ts *testing.T) {
 	expectError(ts, "~{}")
 	expectError(ts, "{}~")
 }
+
+func TestRegexDfa(ts *testing.T) {
+	for _, pt := range NewBeeSyntax() {
+		if pt.Nest == nil && pt.Regex.dfa == nil {
+			ts.Logf(`"%s" is not determinized`, pt.Regex.Src)
+			ts.Fail()
+		}
+	}
+
+	expectMatchEq(ts, "{'a'|'ab'}", "ab", 1)
+	expectMatchEq(ts, "'struct'/!a", "struct {", 6)
+	expectNoMatch(ts, "'struct'/!a", "structure")
+	expectNoMatch(ts, "'struct'/!a", "struct")
+	expectMatchEq(ts, `q {{{'\\'^}|^} ~ /{q|'\n'}} ? {q|'\n'}`, `'a\'b' c`, 6)
+	expectMatchEq(ts, "!{'a'|'b'}+", "xyzab", 3)
+}
+
+func expectModeEq(ts *testing.T, mode RegexMode, src string, expr string, eq int) {
+	regex, err := NewRegexMode(src, mode)
+	if err != nil {
+		ts.Log(err)
+		ts.Fail()
+		return
+	}
+	if match := regex.Match(expr); match != eq {
+		ts.Logf(`"%s" matched %d of "%s" instead of %d`, src, match, expr, eq)
+		ts.Fail()
+	}
+}
+
+func TestRegexLinear(ts *testing.T) {
+	expectModeEq(ts, ModeLinear, "{'a'*}*'!'", "aaaa!", 5)
+	expectModeEq(ts, ModeLinear, "{'a'*}*'!'", "aaaa", -1)
+	expectModeEq(ts, ModeLinear, "{'a'?}+", "aaa", 3)
+	expectModeEq(ts, ModeLinear, "{'a'|'a'}*'!'", strings.Repeat("a", 1000), -1)
+	expectModeEq(ts, ModeLinear, "{{'a'|'a'}~'!'}+", strings.Repeat("a!", 500), 1000)
+}
+
+func benchmarkRegexMode(b *testing.B, mode RegexMode, src string, expr string) {
+	regex, err := NewRegexMode(src, mode)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		regex.Match(expr)
+	}
+}
+
+func BenchmarkRegexPathological(b *testing.B) {
+	src := "{'a'|'a'}*'!'"
+	expr := strings.Repeat("a", 18)
+
+	b.Run("Backtrack", func(b *testing.B) { benchmarkRegexMode(b, ModeBacktrack, src, expr) })
+	b.Run("Linear", func(b *testing.B) { benchmarkRegexMode(b, ModeLinear, src, expr) })
+	b.Run("Dfa", func(b *testing.B) { benchmarkRegexMode(b, ModeDfa, src, expr) })
+}
+
+func BenchmarkRegexWave(b *testing.B) {
+	src := "{{a|a}~'!'}+ '.'"
+	expr := strings.Repeat("ab!", 8)
+
+	b.Run("Backtrack", func(b *testing.B) { benchmarkRegexMode(b, ModeBacktrack, src, expr) })
+	b.Run("Linear", func(b *testing.B) { benchmarkRegexMode(b, ModeLinear, src, expr) })
+	b.Run("Dfa", func(b *testing.B) { benchmarkRegexMode(b, ModeDfa, src, expr) })
+}
+
+func expectGroups(ts *testing.T, src string, expr string, groups ...string) {
+	regex, err := NewRegex(src)
+	if err != nil {
+		ts.Log(err)
+		ts.Fail()
+		return
+	}
+
+	spans := regex.MatchGroups(expr)
+	if len(groups) == 0 {
+		if spans != nil {
+			ts.Logf(`"%s" matched groups %v of "%s"`, src, spans, expr)
+			ts.Fail()
+		}
+		return
+	}
+	if len(spans) != len(groups) {
+		ts.Logf(`"%s" has %d groups instead of %d`, src, len(spans), len(groups))
+		ts.Fail()
+		return
+	}
+	for i, span := range spans {
+		group := "<none>"
+		if span[0] != -1 {
+			group = expr[span[0]:span[1]]
+		}
+		if group != groups[i] {
+			ts.Logf(`"%s" captured "%s" in group %d of "%s" instead of "%s"`, src, group, i, expr, groups[i])
+			ts.Fail()
+		}
+	}
+}
+
+func TestRegexGroups(ts *testing.T) {
+	expectGroups(ts, "'0x' (digits: {[0-9]|[a-f]|[A-F]}+)", "0xff4Az", "0xff4A", "ff4A")
+	expectGroups(ts, "(a+) '=' (n+)", "abc=123;", "abc=123", "abc", "123")
+	expectGroups(ts, "{(n) ','}+", "1,2,3,", "1,2,3,", "3")
+	expectGroups(ts, "(a) {'-' (n)}?", "a", "a", "a", "<none>")
+	expectGroups(ts, "((a)(n))", "a1", "a1", "a1", "a", "1")
+	expectGroups(ts, "('(') (')')", "()", "()", "(", ")")
+	expectGroups(ts, "(a*)'!'", "abc")
+
+	expectMatchEq(ts, "(a+) a", "abc", 3)
+	expectMatchEq(ts, "(x: {a|'_'}+) '=' (y: n+)", "snake_case=10", 13)
+
+	regex, _ := NewRegex("(key: a+) '=' (n+) (value: a+)")
+	if regex.GroupIndex("key") != 1 || regex.GroupIndex("value") != 3 || regex.GroupIndex("x") != -1 {
+		ts.Logf(`Wrong group indices for %v`, regex.Groups)
+		ts.Fail()
+	}
+
+	expectError(ts, "(")
+	expectError(ts, ")")
+	expectError(ts, "(a")
+	expectError(ts, "a)")
+	expectError(ts, "(x: a) (x: n)")
+}
+
+func TestRegexRunes(ts *testing.T) {
+	expectModeEq(ts, ModeRunes, "^", "💡", 4)
+	expectModeEq(ts, ModeDfa, "^", "💡", 1)
+	expectModeEq(ts, ModeRunes, "'rune(' q ^ q ')'", "rune('💡')", 12)
+	expectModeEq(ts, ModeRunes, "[α-ω]+", "λογοςx", 10)
+	expectModeEq(ts, ModeRunes, "[α-ω]+", "Λ", -1)
+	expectModeEq(ts, ModeRunes, "!_+", "çà et là", 4)
+	expectModeEq(ts, ModeRunes|ModeLinear, "{^~'é'}", "ééé", 2)
+	expectModeEq(ts, ModeRunes, "a+", "abç", 2)
+
+	expectModeEq(ts, ModeRunes, "{A|'_'} {A|'_'|D}*", "größe_2 = 3", 9)
+	expectModeEq(ts, ModeDfa, "A+", "héllo wörld", 6)
+	expectModeEq(ts, ModeDfa, "D+", "٣٤5x", 5)
+	expectModeEq(ts, ModeDfa, "D", "x", -1)
+
+	expectMatch(ts, "A", "ж")
+	expectNoMatch(ts, "A", "1")
+	expectNoMatch(ts, "D", "a")
+}
+
+func TestRegexModifiers(ts *testing.T) {
+	expectMatchEq(ts, "{?i: 'struct'}/!a", "STRUCT {", 6)
+	expectMatchEq(ts, "{?i: 'struct'}/!a", "sTrUcT(", 6)
+	expectNoMatch(ts, "{?i: 'struct'}/!a", "Structure")
+	expectMatchEq(ts, "{?i: [a-c]+ n}", "aBC1", 4)
+	expectNoMatch(ts, "{?i: [^a]}", "A")
+	expectMatchEq(ts, "{?i: 'a' {?-i: 'b'}}", "Ab", 2)
+	expectNoMatch(ts, "{?i: 'a' {?-i: 'b'}}", "AB")
+	expectNoMatch(ts, "{?i: 'é'}", "É")
+
+	expectMatchEq(ts, "^*", "ab\ncd", 5)
+	expectMatchEq(ts, "{?-s: ^*}", "ab\ncd", 2)
+	expectMatchEq(ts, "{?-s: {?s: ^} ^}", "\na", 2)
+	expectNoMatch(ts, "{?-s: {?s: ^} ^}", "\n\n")
+
+	expectModeEq(ts, ModeRunes, "{?i: 'é' [α-ω]}", "ÉΛ", 4)
+	expectModeEq(ts, ModeRunes, "{?i: 'k'}", "\u212a", 3)
+	expectModeEq(ts, ModeRunes|ModeLinear, "{?i: 'straße'}", "STRASSE", -1)
+
+	expectErrorAt(ts, "{?x: 'a'}", 2, 'x')
+	expectErrorAt(ts, "{?i-s-i: 'a'}", 5, '-')
+	expectErrorAt(ts, "{?is", 1, '?')
+
+	rx, _ := NewRegex("{?i: 'if'}")
+	if graph := rx.Graph("test"); !strings.Contains(graph, `label="{?i: 'if'}"`) {
+		ts.Logf("Modifiers are missing from the graph labels:\n%s", graph)
+		ts.Fail()
+	}
+}
+
+func expectMatchAt(ts *testing.T, src string, expr string, index int, eq int) {
+	for _, mode := range []RegexMode{ModeDfa, ModeLinear, ModeBacktrack, ModeLongest} {
+		regex, err := NewRegexMode(src, mode)
+		if err != nil {
+			ts.Log(err)
+			ts.Fail()
+			return
+		}
+		if match := regex.MatchAt(expr, index); match != eq {
+			ts.Logf(`"%s" matched %d of "%s" at %d instead of %d in mode %d`, src, match, expr, index, eq, mode)
+			ts.Fail()
+		}
+	}
+}
+
+func TestRegexAnchors(ts *testing.T) {
+	expectMatchAt(ts, "< '#' a+", "x\n#if", 2, 3)
+	expectMatchAt(ts, "< '#' a+", "#if", 0, 3)
+	expectMatchAt(ts, "< '#' a+", "x #if", 2, -1)
+	expectMatchAt(ts, "<a>", "x\nb", 2, 1)
+	expectMatchAt(ts, "<a>", "x\nbc", 2, -1)
+	expectMatchAt(ts, "'a'+ >", "aa\nb", 0, 2)
+	expectMatchAt(ts, "'a'+ >", "aab", 0, -1)
+	expectMatchAt(ts, "n+ $", "x12", 1, 2)
+	expectMatchAt(ts, "n+ $", "12x", 0, -1)
+	expectMatchAt(ts, "% a+ %", "an apple", 3, 5)
+	expectMatchAt(ts, "% a+ %", "an apple", 4, -1)
+	expectMatchAt(ts, "'if' %", "if(", 0, 2)
+	expectMatchAt(ts, "'if' %", "iffy", 0, -1)
+
+	expectErrorAt(ts, "'a' \\", 4, '\\')
+	expectMatchAt(ts, "\\'0x' [0-9a-f]+", "0x1f", 2, 2)
+	expectMatchAt(ts, "\\'0x' [0-9a-f]+", "1f", 0, -1)
+	expectMatchAt(ts, "\\{'a'|'ab'} 'c'", "abc", 2, 1)
+	expectMatchAt(ts, "\\{a+ ':'} n+", "key:12", 4, 2)
+	expectMatchAt(ts, "\\{a+ ':'} n+", "12:12", 3, -1)
+	expectMatchAt(ts, "'a' \\'a'", "a", 0, 1)
+	expectMatchAt(ts, "{'x' \\'yx'}+", "yxxx", 1, 1)
+
+	rx, _ := NewRegex("< '#' a+")
+	if spans := rx.FindAll("#a b #c\n#d", -1); !reflect.DeepEqual(spans, [][2]int{{0, 2}, {8, 10}}) {
+		ts.Logf("Found %v", spans)
+		ts.Fail()
+	}
+	rx, _ = NewRegex("% 'in' %")
+	if replaced := rx.Replace("in inside bin in", "x"); replaced != "x inside bin x" {
+		ts.Logf(`Replaced into "%s"`, replaced)
+		ts.Fail()
+	}
+}
+
+// Matching the prefixes of expr without eof either asks for more input or decides the
+// match of the whole expr
+func expectMatchMore(ts *testing.T, src string, expr string, index int) {
+	for _, mode := range []RegexMode{ModeDfa, ModeLinear, ModeBacktrack, ModeLongest} {
+		regex, err := NewRegexMode(src, mode)
+		if err != nil {
+			ts.Log(err)
+			ts.Fail()
+			return
+		}
+		whole := regex.MatchAt(expr, index)
+		for end := index; end <= len(expr); end++ {
+			match, more := regex.MatchMore(expr[:end], index, false)
+			if !more && match != whole {
+				ts.Logf(`"%s" decided %d on "%s" at %d instead of %d on "%s" in mode %d`, src, match, expr[:end], index, whole, expr, mode)
+				ts.Fail()
+			}
+		}
+		if match, more := regex.MatchMore(expr, index, true); more || match != whole {
+			ts.Logf(`"%s" matched %d of "%s" at %d with eof instead of %d in mode %d`, src, match, expr, index, whole, mode)
+			ts.Fail()
+		}
+	}
+}
+
+func TestRegexMore(ts *testing.T) {
+	expectMatchMore(ts, "'struct'/!a", "struct x", 0)
+	expectMatchMore(ts, "'struct'/!a", "structure", 0)
+	expectMatchMore(ts, "'ab'+", "ababx", 0)
+	expectMatchMore(ts, "n+", "x12", 1)
+	expectMatchMore(ts, "{[0-9]+ '.' [0-9]*} | {[0-9]* '.' [0-9]+}", "12.5e", 0)
+	expectMatchMore(ts, `q {{'\\'^}|^ ~ /{q|'\n'}} ? {q|'\n'}`, `"a\"b" c`, 0)
+	expectMatchMore(ts, "'a'*? 'b'", "aaab", 0)
+	expectMatchMore(ts, "{?i: 'if'} %", "IF x", 0)
+	expectMatchMore(ts, "'a'+ >", "aa\nb", 0)
+	expectMatchMore(ts, "n+ $", "x12", 1)
+	expectMatchMore(ts, "\\{a+ ':'} n+", "key:12;", 4)
+
+	rx, _ := NewRegexMode("[\u00e0-\u00ff]+", ModeRunes|ModeLinear)
+	if match, more := rx.MatchMore("\u00e9\xc3", 0, false); !more {
+		ts.Logf("Matched %d without asking for the rest of a cut rune", match)
+		ts.Fail()
+	}
+	rx, _ = NewRegex("'ab'")
+	if match, more := rx.MatchMore("abc", 0, false); more || match != 2 {
+		ts.Logf("Matched %d, more %t past a decided match", match, more)
+		ts.Fail()
+	}
+}
+
+func TestRegexFragments(ts *testing.T) {
+	fragments := NewFragments()
+	for name, src := range map[string]string{
+		"hexdigit": "[0-9a-fA-F]",
+		"idhead":   "a|'_'",
+		"ident":    "<:idhead> {<:idhead>|n}*",
+		"kw":       "'select'",
+		"a":        "'x' <:b>",
+		"b":        "<:a>?",
+		"self":     "'(' <:self>? ')'",
+		"group":    "(n)",
+		"empty":    "",
+	} {
+		if err := fragments.Define(name, src); err != nil {
+			ts.Fatal(err)
+		}
+	}
+	if fragments.Define("kw", "'from'") == nil || fragments.Define("1x", "n") == nil {
+		ts.Log("Fragment redefined or defined with an invalid name")
+		ts.Fail()
+	}
+
+	expect := func(src string, mode RegexMode, expr string, eq int) {
+		rx, err := NewRegexFragments(src, mode, fragments)
+		if err != nil {
+			ts.Log(err)
+			ts.Fail()
+			return
+		}
+		if match := rx.Match(expr); match != eq {
+			ts.Logf(`"%s" matched %d of "%s" instead of %d`, src, match, expr, eq)
+			ts.Fail()
+		}
+	}
+	expect("'0x' <:hexdigit>+", ModeDfa, "0x1fz", 4)
+	expect("<:ident> '=' <:ident>", ModeDfa, "ab_1=x2 ", 7)
+	expect("<:hexdigit>#2 <:hexdigit>", ModeLinear, "c0ffee", 3)
+	expect("<:kw>", ModeDfa, "SELECT", -1)
+	expect("{?i: <:kw>} _ <:kw>", ModeDfa, "SELECT select", 13)
+	expect("< '#' <:ident> >", ModeRunes, "#if", 3)
+	// Anchors around a name are not a reference, even to a defined fragment
+	expect("<a>", ModeLinear, "b\nx", 1)
+	expect("<a>", ModeLinear, "bc", -1)
+
+	expectError := func(src string, offset int, desc string) {
+		_, err := NewRegexFragments(src, ModeDfa, fragments)
+		rxErr, ok := err.(*RegexError)
+		if !ok || rxErr.Offset != offset || !strings.Contains(rxErr.Desc, desc) {
+			ts.Logf(`"%s" expected an error at %d with "%s", got %v`, src, offset, desc, err)
+			ts.Fail()
+		}
+	}
+	expectError("n <:nope>", 2, "Unknown fragment")
+	expectError("<:a>", 0, "<:a> -> <:b> -> <:a>")
+	expectError("<:self>", 4, "<:self> -> <:self>")
+	expectError("'x' <:group>", 0, "Capture groups are not allowed")
+	expectError("<:empty>+", 0, "Fragment is empty")
+
+	if _, err := NewRegex("<:hexdigit>"); err == nil {
+		ts.Log("Fragment resolved without fragments")
+		ts.Fail()
+	}
+}
+
+func TestRegexFind(ts *testing.T) {
+	expectFind := func(mode RegexMode, src string, expr string, finds ...string) {
+		regex, err := NewRegexMode(src, mode)
+		if err != nil {
+			ts.Log(err)
+			ts.Fail()
+			return
+		}
+
+		spans := regex.FindAll(expr, -1)
+		if len(spans) != len(finds) {
+			ts.Logf(`"%s" found %v in "%s" instead of %q`, src, spans, expr, finds)
+			ts.Fail()
+			return
+		}
+		for i, span := range spans {
+			if expr[span[0]:span[1]] != finds[i] {
+				ts.Logf(`"%s" found "%s" in "%s" instead of "%s"`, src, expr[span[0]:span[1]], expr, finds[i])
+				ts.Fail()
+			}
+		}
+
+		begin, end := regex.Find(expr)
+		if len(finds) == 0 && begin != -1 || len(finds) != 0 && [2]int{begin, end} != spans[0] {
+			ts.Logf(`"%s" first found [%d, %d] in "%s"`, src, begin, end, expr)
+			ts.Fail()
+		}
+	}
+
+	expectFind(ModeDfa, "n+", "a12b345c6", "12", "345", "6")
+	expectFind(ModeDfa, "'struct'/!a", "struct structure struct{", "struct", "struct")
+	expectFind(ModeDfa, "'0x' [0-9a-f]+", "x := 0xff + 0x10", "0xff", "0x10")
+	expectFind(ModeDfa, "'//' ^~/'\n'", "a // one\nb // two\n", "// one", "// two")
+	expectFind(ModeDfa, "n*", "a12b", "", "12", "")
+	expectFind(ModeDfa, "'z'", "abc")
+	expectFind(ModeDfa, "'a'", "")
+	expectFind(ModeRunes, "n*", "é1", "", "1")
+	expectFind(ModeLinear, "{a|'_'} {a|'_'|n}*", "x1 := y_2", "x1", "y_2")
+
+	regex, _ := NewRegex("n+")
+	if spans := regex.FindAll("1 2 3 4", 2); len(spans) != 2 {
+		ts.Logf(`"%s" found %v instead of 2 spans`, regex.Src, spans)
+		ts.Fail()
+	}
+	if replaced := regex.Replace("a1b22c", "#"); replaced != "a#b#c" {
+		ts.Logf(`"%s" replaced into "%s"`, regex.Src, replaced)
+		ts.Fail()
+	}
+	double := func(s string) string { return s + s }
+	if replaced := regex.ReplaceFunc("a1b22c", double); replaced != "a11b2222c" {
+		ts.Logf(`"%s" replaced into "%s"`, regex.Src, replaced)
+		ts.Fail()
+	}
+	if replaced := regex.Replace("abc", "#"); replaced != "abc" {
+		ts.Logf(`"%s" replaced into "%s"`, regex.Src, replaced)
+		ts.Fail()
+	}
+}
+
+func TestRegexLongest(ts *testing.T) {
+	expectModeEq(ts, ModeLongest, "{'a'|'ab'}", "ab", 2)
+	expectModeEq(ts, ModeDfa, "{'a'|'ab'}", "ab", 1)
+	expectModeEq(ts, ModeLongest, "^~'c'", "abcabc", 6)
+	expectModeEq(ts, ModeLongest, "{'<'|'<='|'<<'}", "<=x", 2)
+	expectModeEq(ts, ModeLongest, "{'if'|'iffy'}", "iffy", 4)
+	expectModeEq(ts, ModeLongest, "{'a'|'ab'} 'c'", "abd", -1)
+	expectModeEq(ts, ModeLongest, "'a'?", "b", 0)
+	expectModeEq(ts, ModeLongest, "{'a'*}*'!'", "aaa!", 4)
+
+	expectModeEq(ts, ModeLongest|ModeLinear, "{'a'|'ab'}", "ab", 2)
+	expectModeEq(ts, ModeLongest|ModeLinear, "^~'c'", "abcabc", 6)
+	expectModeEq(ts, ModeLongest|ModeLinear, "{'a'|'ab'} /!'ab'", "abab", 1)
+	expectModeEq(ts, ModeLongest|ModeLinear, "{'a'|'ab'} /!'x'", "abab", 2)
+	expectModeEq(ts, ModeLongest|ModeRunes, "{^|'éé'}", "éé", 4)
+	expectModeEq(ts, ModeLongest|ModeRunes, "{^|'éé'}", "é", 2)
+
+	regex, _ := NewRegexMode("{('x')|('x' 'y')}", ModeLongest)
+	if spans := regex.MatchGroups("xyz"); spans == nil || spans[0] != [2]int{0, 2} || spans[1][0] != -1 || spans[2] != [2]int{0, 2} {
+		ts.Logf(`"%s" captured %v`, regex.Src, spans)
+		ts.Fail()
+	}
+}
+
+func TestRegexError(ts *testing.T) {
+	expectErrorAt(ts, "N", 0, 'N')
+	expectErrorAt(ts, "'a' N", 4, 'N')
+	expectErrorAt(ts, "}", 0, '}')
+	expectErrorAt(ts, "'a' )", 4, ')')
+	expectErrorAt(ts, "a]", 1, ']')
+	expectErrorAt(ts, "{'abc'", 0, '{')
+	expectErrorAt(ts, "'x' {{'a'}", 4, '{')
+	expectErrorAt(ts, "{(a", 1, '(')
+	expectErrorAt(ts, "n (x: a) (x: n)", 10, '(')
+
+	expectErrorAt(ts, "+", 0, '+')
+	expectErrorAt(ts, "'a' | *", 6, '*')
+	expectErrorAt(ts, "{}?", 2, '?')
+	expectErrorAt(ts, "a|", 1, '|')
+	expectErrorAt(ts, "|a", 0, '|')
+	expectErrorAt(ts, "a ~ {}", 2, '~')
+	expectErrorAt(ts, "!", 0, '!')
+	expectErrorAt(ts, "a /", 2, '/')
+
+	expectErrorAt(ts, "[0-9", 0, '[')
+	expectErrorAt(ts, "'0x' [0-9a-f", 5, '[')
+	expectErrorAt(ts, "[0-9z-a]", 4, '-')
+	expectErrorAt(ts, "{n [a-cd-b]}", 7, '-')
+
+	expectErrorAt(ts, "'abc", 0, '\'')
+	expectErrorAt(ts, "n `abc", 2, '`')
+	expectErrorAt(ts, `'\q'`, 1, '\\')
+	expectErrorAt(ts, `'ab\x4'`, 3, '\\')
+	expectErrorAt(ts, `'\u{110000}'`, 1, '\\')
+	expectErrorAt(ts, `'\u{41'`, 1, '\\')
+	expectErrorAt(ts, `'abc\`, 4, '\\')
+
+	expectErrorAt(ts, "#3", 0, '#')
+	expectErrorAt(ts, "n#", 1, '#')
+	expectErrorAt(ts, "n #4,2", 2, '#')
+	expectErrorAt(ts, "n#100000", 1, '#')
+
+	_, err := NewRegex("'0x' [0-9a-f")
+	if expected := "regex > '0x' [0-9a-f\n             ^ Unmatched scope brace, missing <]> token"; err.Error() != expected {
+		ts.Logf("Error rendered as\n%s\ninstead of\n%s", err, expected)
+		ts.Fail()
+	}
+	_, err = NewRegex("'a'\n[z-a]")
+	if expected := "regex > [z-a]\n         ^ 'z-a': Scope is not matchable because the interval of characters is empty"; err.Error() != expected {
+		ts.Logf("Error rendered as\n%s\ninstead of\n%s", err, expected)
+		ts.Fail()
+	}
+	_, err = NewRegex("'a'\n\t\t'é€' N")
+	if expected := "regex > 'é€' N\n             ^ 'N': Unrecognized token in regex, none of [_aonQqAD^'{}()!/\\<>$%|?*+~#]"; err.Error() != expected {
+		ts.Logf("Error rendered as\n%s\ninstead of\n%s", err, expected)
+		ts.Fail()
+	}
+}
+
+func expectTrace(ts *testing.T, src string, expr string, match int, path []string, fail string) {
+	rx, err := NewRegex(src)
+	if err != nil {
+		ts.Log(err)
+		ts.Fail()
+		return
+	}
+
+	trace := rx.Trace(expr)
+	states := make([]string, 0, len(trace.Path))
+	for _, id := range trace.Path {
+		step := trace.Steps[id]
+		states = append(states, fmt.Sprintf("%s@%d", step.State, step.Index))
+	}
+	if trace.Match != match || strings.Join(states, " ") != strings.Join(path, " ") {
+		ts.Logf(`"%s" traced %d of "%s" along %v instead of %d along %v`, src, trace.Match, expr, states, match, path)
+		ts.Fail()
+	}
+
+	failed := ""
+	if trace.Fail != -1 {
+		step := trace.Steps[trace.Fail]
+		failed = fmt.Sprintf("%s@%d", step.State, step.Index)
+	}
+	if failed != fail {
+		ts.Logf(`"%s" failed on "%s" of "%s" instead of "%s"`, src, failed, expr, fail)
+		ts.Fail()
+	}
+}
+
+func TestRegexTrace(ts *testing.T) {
+	expectTrace(ts, "'0x' n+", "0x12", 4, []string{"'0x'@0", "[0..9]@2", "[0..9]@3"}, "")
+	expectTrace(ts, "'0x' n+", "0xg", -1, []string{"'0x'@0", "[0..9]@2"}, "[0..9]@2")
+	expectTrace(ts, "{'ab'|'a'} !n", "a1", -1, []string{"&Sigma;@0", "'a'@0", "!@1"}, "!@1")
+	expectTrace(ts, "a+ /'!'", "ab!", 2, []string{"[A..z]@0", "[A..z]@1", "/@2"}, "")
+	expectTrace(ts, "{'a'*}*'!'", "aaa?", -1, []string{"&Sigma;@0", "&Sigma;@0", "'a'@0", "&Sigma;@1", "&Sigma;@1", "'a'@1", "&Sigma;@2", "&Sigma;@2", "'a'@2", "&Sigma;@3", "&Sigma;@3", "&Sigma;@3", "'!'@3"}, "'!'@3")
+
+	rx, _ := NewRegex("{'ab'|'a'} !n")
+	trace := rx.Trace("a1")
+	data, err := trace.Json()
+	if err != nil {
+		ts.Log(err)
+		ts.Fail()
+	}
+	var decoded RegexTrace
+	if err := json.Unmarshal(data, &decoded); err != nil || !reflect.DeepEqual(decoded, trace) {
+		ts.Logf("Trace does not survive a JSON round trip: %v\n%s", err, data)
+		ts.Fail()
+	}
+
+	graph := rx.TraceGraph("test", "a1")
+	if strings.Count(graph, `color="#C62828"`) != 1 || strings.Count(graph, `fillcolor="#CDEFC4"`) != 2 {
+		ts.Logf("Traced graph does not color the path and the failing edge\n%s", graph)
+		ts.Fail()
+	}
+}
+
+func expectCanonical(ts *testing.T, src string, canonical string, simple bool) {
+	rx, err := NewRegex(src)
+	if err != nil {
+		ts.Log(err)
+		ts.Fail()
+		return
+	}
+	if simple {
+		rx = rx.Simplify()
+	}
+
+	printed, err := rx.Canonical()
+	if err != nil || printed != canonical {
+		ts.Logf(`"%s" printed as "%s" instead of "%s": %v`, src, printed, canonical, err)
+		ts.Fail()
+	}
+}
+
+// The canonical source parses into a graph printed the same way and matching the same
+func expectRoundTrip(ts *testing.T, src string, exprs ...string) {
+	rx, err := NewRegex(src)
+	if err != nil {
+		ts.Log(err)
+		ts.Fail()
+		return
+	}
+	expectRegexRoundTrip(ts, rx, exprs...)
+}
+
+func expectRegexRoundTrip(ts *testing.T, rx Regex, exprs ...string) {
+	src := rx.Src
+	for _, simple := range []bool{false, true} {
+		printed := rx
+		if simple {
+			printed = rx.Simplify()
+		}
+		canonical, err := printed.Canonical()
+		if err != nil {
+			ts.Logf(`"%s" has no canonical source: %v`, src, err)
+			ts.Fail()
+			continue
+		}
+		reparsed, err := NewRegex(canonical)
+		if err != nil {
+			ts.Logf(`"%s" canonical source "%s" does not parse: %v`, src, canonical, err)
+			ts.Fail()
+			continue
+		}
+		if simple {
+			reparsed = reparsed.Simplify()
+		}
+		if again, _ := reparsed.Canonical(); again != canonical {
+			ts.Logf(`"%s" printed as "%s" then as "%s"`, src, canonical, again)
+			ts.Fail()
+		}
+
+		for _, expr := range exprs {
+			for i := range expr {
+				match := rx.Match(expr[i:])
+				if other := make(memo).Submit(printed.Head, expr[i:], 0); other != match {
+					ts.Logf(`"%s" matched %d of "%s" but its simplified graph matched %d`, src, match, expr[i:], other)
+					ts.Fail()
+				}
+				if other := make(memo).Submit(reparsed.Head, expr[i:], 0); other != match {
+					ts.Logf(`"%s" matched %d of "%s" but "%s" matched %d`, src, match, expr[i:], canonical, other)
+					ts.Fail()
+				}
+			}
+		}
+	}
+}
+
+func TestRegexCanonical(ts *testing.T) {
+	expectCanonical(ts, "'0x'  n+", "'0x' n+", false)
+	expectCanonical(ts, "{'a'} {{_}}", "'a' _", false)
+	expectCanonical(ts, "a n|o _", "a n|o _", false)
+	expectCanonical(ts, "{{a|n}|o}", "a|n|o", false)
+	expectCanonical(ts, "{a n}?", "{a n}?", false)
+	expectCanonical(ts, "{a|n}*", "{a|n}*", false)
+	expectCanonical(ts, "{a* n}+", "{a* n}+", false)
+	expectCanonical(ts, "'/*' {^ ~ '*/'}", "'/*' ^~'*/'", false)
+	expectCanonical(ts, "'/*' ^*? '*/'", "'/*' ^*? '*/'", false)
+	expectCanonical(ts, "{a n}?? a+?", "{a n}?? a a*?", false)
+	expectCanonical(ts, "(x: a+) (n)", "(x: a+) (n)", false)
+	expectCanonical(ts, "'struct'/!a", "'struct' /!a", false)
+	expectCanonical(ts, "!{a n}", "!{a n}", false)
+	expectCanonical(ts, "n#2,3", "n n n?", false)
+	expectCanonical(ts, `'\'\\\n\x01é'`, `'\'\\\n\x01é'`, false)
+	expectCanonical(ts, "[]a-z]", "[]a-z]", false)
+	expectCanonical(ts, "[x^]", "[x^]", false)
+	expectCanonical(ts, "[^-z]", "[^-z]", false)
+	expectCanonical(ts, "A D", "A D", false)
+	expectCanonical(ts, "< \\{'0x'} n+ $", "< \\'0x' n+ $", false)
+	expectCanonical(ts, "{?i-s: 'if' ^ {?s: ^}}", "{?i: 'if'} {?-s: ^} ^", false)
+
+	for _, pt := range NewBeeSyntax() {
+		if pt.Nest != nil {
+			continue
+		}
+		expectRegexRoundTrip(ts, pt.Regex, LoremIpsum[:200], `'it\'s' "a" 0x1F 0b01 12.5 abc_1 <= :: '\n`)
+	}
+	expectRoundTrip(ts, "{'ab'|'a'} {'c'|'bc'}", "abc", "abbc", "ac")
+	expectRoundTrip(ts, "{a*}* '!'", "aaa!", "aa a!")
+	expectRoundTrip(ts, "{a|_}#1, (n)?", "ab c1", " 2")
+	expectRoundTrip(ts, "{'a' 'b'}+ 'a'? 'b'+", "ababab", "abab bb")
+	expectRoundTrip(ts, "'/*' ^*? '*/' {a|n}+? n?? '!'", "/**/a1!", "/* */ */ab12!")
+}
+
+func TestRegexSimplify(ts *testing.T) {
+	expectCanonical(ts, "{[0-9]|[a-f]|[A-F]}+", "[0-9A-Fa-f]+", true)
+	expectCanonical(ts, "{a|'_'} {a|'_'|n}*", "[A-Z_a-z] [0-9A-Z_a-z]*", true)
+	expectCanonical(ts, "'a' 'b' {'c' 'd'}", "'abcd'", true)
+	expectCanonical(ts, "'a' 'b'+", "'a' 'b'+", true)
+	expectCanonical(ts, "{'a' 'b'}+", "'ab'+", true)
+	expectCanonical(ts, "{{'a'|'b'}|'c'} n", "[a-c] n", true)
+	expectCanonical(ts, "{'ab'|n}|'c'", "'ab'|[0-9c]", true)
+	expectCanonical(ts, "'^'|[_-z]", "[_-z^]", true)
+	expectCanonical(ts, "'+'|[--/]", "[+-+--/]", true)
+	expectCanonical(ts, "']'|'a'|'-'", "[]-]-a]", true)
+	expectCanonical(ts, "!{'a'|'b'}", "![a-b]", true)
+
+	rx, _ := NewRegex("{{'a'|'b'}|'c'}")
+	if len(rx.Simplify().Head.unique()) != 1 {
+		ts.Log("Simplified graph still has epsilon nodes")
+		ts.Fail()
+	}
+}
+
+func expectRE2(ts *testing.T, src string, re2 string) {
+	rx, err := NewRegex(src)
+	if err != nil {
+		ts.Log(err)
+		ts.Fail()
+		return
+	}
+	translated, err := rx.RE2()
+	if (re2 == "") != (err != nil) || translated != re2 {
+		ts.Logf(`"%s" translated to RE2 "%s" instead of "%s": %v`, src, translated, re2, err)
+		ts.Fail()
+	}
+}
+
+func expectFromRE2(ts *testing.T, re2 string, src string) {
+	translated, err := FromRE2(re2)
+	if (src == "") != (err != nil) || translated != src {
+		ts.Logf(`RE2 "%s" translated to "%s" instead of "%s": %v`, re2, translated, src, err)
+		ts.Fail()
+	}
+}
+
+// Length of the match at the beginning of expr with the regexp package, -1 without a match
+func matchRE2(re *regexp.Regexp, expr string) int {
+	if loc := re.FindStringIndex(expr); loc != nil {
+		return loc[1]
+	}
+	return -1
+}
+
+func TestRegexRE2(ts *testing.T) {
+	expectRE2(ts, "'0x' {[0-9]|[a-f]|[A-F]}+", `0x[0-9A-Fa-f]+`)
+	expectRE2(ts, "{a|'_'} {a|'_'|n}*", `[A-Z_a-z][0-9A-Z_a-z]*`)
+	expectRE2(ts, "'a.b'|'(c)'?", `(?:a\.b|\(c\))?`)
+	expectRE2(ts, "'/*' ^~'*/'", `/\*(?s:.)*?\*/`)
+	expectRE2(ts, "(x: n+) ('\n')", `(?P<x>[0-9]+)(\n)`)
+	expectRE2(ts, "!Q [^a-z] A !D", `[^"][^a-z]\pL\P{Nd}`)
+	expectRE2(ts, "[0-9]#2,4 _#1,", `[0-9][0-9](?:[0-9][0-9]?)?[\x{8}-\f\x{20}][\x{8}-\f\x{20}]*`)
+	expectRE2(ts, "{?i: 'select'} _+ {?-s: ^}*", `(?i:select)[\x{8}-\f\x{20}]+.*`)
+	expectRE2(ts, "< '#' a+ % >", `(?m:^)#[A-Za-z]+\b(?m:$)`)
+	expectRE2(ts, "'struct'/!a", "")
+	expectRE2(ts, "\\'0x' n+", "")
+	expectRE2(ts, "!{'a' 'b'}", "")
+}
+
+func TestRegexFromRE2(ts *testing.T) {
+	expectFromRE2(ts, `0x[0-9a-fA-F]+`, "'0x' [0-9A-Fa-f]+")
+	expectFromRE2(ts, `(?P<x>\d+)\.(\d*)`, "(x: [0-9]+) '.' ([0-9]*)")
+	expectFromRE2(ts, `/\*.*?\*/`, "'/*' {?-s: ^}*? '*/'")
+	expectFromRE2(ts, `a+?b*?c??`, "'a'+? 'b'*? 'c'??")
+	expectFromRE2(ts, `(?s).\pL\p{Nd}`, "^ A D")
+	expectFromRE2(ts, `[^a-c]x{2,}|y{3}|z{1,2}|`, "{{[^a-c] 'x'#2,}|{'y'#3}|{'z'#1,2}}?")
+	expectFromRE2(ts, `^a`, "'a'")
+	expectFromRE2(ts, `^a|^b`, "'a'|'b'")
+	expectFromRE2(ts, `a^b`, "")
+	expectFromRE2(ts, `a|ab`, "'a' 'b'??")
+	expectFromRE2(ts, `if|in|int`, "'i' 'f'|{'n' 't'??}")
+	expectFromRE2(ts, `a(?:b||c)`, "'a' 'b'|''|'c'")
+	expectFromRE2(ts, `(?m)^a$\b\z`, "< 'a' > % $")
+	expectFromRE2(ts, `a\B`, "")
+	expectFromRE2(ts, `(?i)ab`, "{?i: 'AB'}")
+	expectFromRE2(ts, `a{2,3}?`, "")
+
+	expectRoundTripRE2(ts, `a|ab`, "ab", "b")
+	expectRoundTripRE2(ts, `if|in|int`, "int", "in", "if", "i")
+	expectRoundTripRE2(ts, `foo|foobar`, "foobar", "foob")
+	expectRoundTripRE2(ts, `^foo`, "foo", "xfoo")
+	expectRoundTripRE2(ts, `(?:|a)b`, "ab", "b")
+	rx, err := NewRegex("{'a'|'ab'} 'c'")
+	if err != nil {
+		ts.Fatal(err)
+	}
+	re2, err := rx.RE2()
+	if err != nil {
+		ts.Fatal(err)
+	}
+	expectRoundTripRE2(ts, re2, "abc", "ac", "ab")
+}
+
+// The translation of the RE2 pattern matches the same prefixes of exprs as the regexp
+// package
+func expectRoundTripRE2(ts *testing.T, re2 string, exprs ...string) {
+	src, err := FromRE2(re2)
+	if err != nil {
+		ts.Logf(`RE2 "%s" has no translation: %v`, re2, err)
+		ts.Fail()
+		return
+	}
+	rx, err := NewRegex(src)
+	if err != nil {
+		ts.Logf(`RE2 "%s" translated to invalid "%s": %v`, re2, src, err)
+		ts.Fail()
+		return
+	}
+	re := regexp.MustCompile(`^(?:` + re2 + `)`)
+	for _, expr := range exprs {
+		if match, other := rx.Match(expr), matchRE2(re, expr); match != other {
+			ts.Logf(`RE2 "%s" matched %d of "%s" but "%s" matched %d`, re2, other, expr, src, match)
+			ts.Fail()
+		}
+	}
+}
+
+// Bee regexes translated to RE2 match the same prefix as the regexp package
+func FuzzRegexRE2(f *testing.F) {
+	f.Add("'0x' [0-9a-fA-F]+", "0x1fz")
+	f.Add("{[0-9]+ '.' [0-9]*} | {[0-9]* '.' [0-9]+}", "12.5e3")
+	f.Add("{a|'_'} {a|'_'|n}*", "abc_12 ")
+	f.Add("Q^Q", `"x"`)
+	f.Add("^~_", "abc def")
+	f.Add("{'ab'|'a'} {'c'|'bc'}", "abc")
+	f.Add("(x: a+)? !n n#2,3", "ab+123")
+	f.Add("{'a'*}* 'b'", "aaab")
+	f.Add("{?i: 'ab' [c-e]} {?-s: ^}", "AbD\n")
+	f.Add("{a+ % o >}+ '\n' < $", "ab.\n")
+	f.Add("a+? n*? {'x'|'y'}?? 'y'", "ab12yy")
+
+	f.Fuzz(func(t *testing.T, src string, expr string) {
+		rx, err := NewRegexMode(src, ModeRunes)
+		if err != nil || rx.Head == nil || !utf8.ValidString(expr) {
+			return
+		}
+		re2, err := rx.RE2()
+		if err != nil {
+			return
+		}
+		re, err := regexp.Compile(`^(?:` + re2 + `)`)
+		if err != nil {
+			t.Fatalf(`"%s" translated to invalid RE2 "%s": %v`, src, re2, err)
+		}
+		if match, other := rx.Match(expr), matchRE2(re, expr); match != other {
+			t.Fatalf(`"%s" matched %d of "%s" but RE2 "%s" matched %d`, src, match, expr, re2, other)
+		}
+	})
+}
+
+// RE2 patterns translated to bee regexes match the same prefix as the regexp package
+func FuzzRegexFromRE2(f *testing.F) {
+	f.Add(`0x[0-9a-fA-F]+`, "0x1fz")
+	f.Add(`[a-z_][a-z0-9_]*`, "snake_case1 ")
+	f.Add(`/\*.*?\*/`, "/* a */ b */")
+	f.Add(`(a|ab)(c|bcd)`, "abcd")
+	f.Add(`^if|in|int`, "int")
+	f.Add(`"(?:\\.|[^"\\])*"`, `"a\"b" c`)
+	f.Add(`x{2,3}y?z+?w`, "xxxyzzw")
+	f.Add(`(?i)se[l-m]ect.`, "SELECT\n")
+	f.Add(`(?m)a+\b.$\n^b\z`, "aa-\nb")
+
+	f.Fuzz(func(t *testing.T, re2 string, expr string) {
+		re, err := regexp.Compile(`^(?:` + re2 + `)`)
+		if err != nil || !utf8.ValidString(expr) {
+			return
+		}
+		src, err := FromRE2(re2)
+		if err != nil {
+			return
+		}
+		rx, err := NewRegexMode(src, ModeRunes)
+		if err != nil {
+			t.Fatalf(`RE2 "%s" translated to invalid "%s": %v`, re2, src, err)
+		}
+		if match, other := rx.Match(expr), matchRE2(re, expr); match != other {
+			t.Fatalf(`RE2 "%s" matched %d of "%s" but "%s" matched %d`, re2, other, expr, src, match)
+		}
+	})
+}
+
+func TestRegexCommand(ts *testing.T) {
+	table := strings.Join([]string{
+		"// Comments and blank lines are skipped",
+		"",
+		"'a'+\t\"aab\"\t\"aa\"",
+		"'\\n' ^\t\"\\nx\"\t\"\\nx\"",
+		"'b'\t\"aab\"\tnone",
+	}, "\n")
+	var stdout, stderr bytes.Buffer
+	if code := regexTest("table", strings.NewReader(table), &stdout, &stderr); code != exitOk {
+		ts.Logf("Table failed with %d:\n%s%s", code, stdout.String(), stderr.String())
+		ts.Fail()
+	}
+
+	failing := map[string]string{
+		"'a'\t\"aab\"\t\"aa\"": `table:1: "'a'" matched "a" of "aab", expected "aa"`,
+		"'b'\t\"aab\"\t\"b\"":  `table:1: "'b'" does not match "aab", expected "b"`,
+		"'a'\t\"aab\"\tnone":   `table:1: "'a'" matched "a" of "aab", expected none`,
+		"'a'\taab\tnone":       `table:1: Input aab is not a quoted string`,
+		"'a'\t\"aab\"":         `table:1: 2 fields instead of PATTERN <tab> INPUT <tab> EXPECTED`,
+		"{\t\"aab\"\tnone":     `table:1: regex > {`,
+	}
+	for line, failure := range failing {
+		stdout.Reset()
+		if code := regexTest("table", strings.NewReader(line), &stdout, &stderr); code != exitFailure || !strings.HasPrefix(stdout.String(), failure) {
+			ts.Logf("Table %q exited with %d:\n%s", line, code, stdout.String())
+			ts.Fail()
+		}
+	}
+
+	commands := []struct {
+		args   []string
+		code   int
+		stdout string
+	}{
+		{[]string{"match", "'a'+", "aab"}, exitOk, "aa\n"},
+		{[]string{"match", "'b'", "aab"}, exitFailure, ""},
+		{[]string{"match", "{", "aab"}, exitFailure, ""},
+		{[]string{"graph", "'a'|'b'"}, exitOk, "strict digraph {"},
+		{[]string{"graph"}, exitUsage, ""},
+		{[]string{"test", "missing.txt"}, exitFailure, ""},
+		{[]string{}, exitUsage, ""},
+	}
+	for _, cmd := range commands {
+		stdout.Reset()
+		if code := regexCommand(cmd.args, &stdout, &stderr); code != cmd.code || !strings.HasPrefix(stdout.String(), cmd.stdout) {
+			ts.Logf("bee regex %s exited with %d instead of %d:\n%s", strings.Join(cmd.args, " "), code, cmd.code, stdout.String())
+			ts.Fail()
+		}
+	}
+}
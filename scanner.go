@@ -1,43 +1,236 @@
 package main
 
+import (
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// Trait and length of the token at the index of src, None and -1 without a match. The
+// first pattern matching wins unless longest, ties then go to the pattern defined first.
+// Unless eof, src may be followed by more input and more tells that the token depends on
+// it
+type Lexer func(src string, index int, longest bool, eof bool) (trait Trait, length int, more bool)
+
+// Size of the chunks read by a Scanner from an io.Reader
+const scannerChunk = 4096
+
 type Scanner struct {
-	src  string
-	cur  int
-	smap SyntaxMap
+	src string
+	cur int
+	lex Lexer
+	// Pick the longest match across all patterns instead of the first pattern
+	// matching, ties go to the pattern defined first
+	Longest bool
+	// Traits of the tokens dropped and of the trivia, see SyntaxMap.Skipped and
+	// SyntaxMap.Trivia
+	skipped map[Trait]bool
+	trivia  map[Trait]bool
+	// File id of the token positions, pos is the position of cur
+	File int
+	pos  Position
+	// Token after cur with its leading trivia, scanned ahead by Finished
+	next *Token
+	// Reader appending to src, the scanned input is discarded except for the history
+	// bytes before cur. src begins at the base offset of the input and is the content of
+	// buf, appending to it leaves the previous src untouched
+	r       io.Reader
+	buf     *strings.Builder
+	base    int
+	history int
+	eof     bool
+	err     error
 }
 
 func NewScanner(src string, sm SyntaxMap) Scanner {
-	return Scanner{src, 0, sm}
+	sn := NewLexerScanner(src, sm.Lex)
+	sn.skipped, sn.trivia = sm.Skipped(), sm.Trivia()
+	return sn
+}
+
+// Scanner of a lexer generated from a syntax map, see SyntaxMap.Generate
+func NewLexerScanner(src string, lex Lexer) Scanner {
+	return Scanner{src: src, cur: 0, lex: lex, skipped: map[Trait]bool{Blank: true}, trivia: map[Trait]bool{Comment: true}, eof: true, pos: Position{Line: 1, Col: 1}}
+}
+
+// Scanner reading the source from r as tokens are scanned, token indices stay offsets
+// in the whole input. Patterns with lookbehinds of unbounded width keep the whole input
+// in memory, see SyntaxMap.History
+func NewReaderScanner(r io.Reader, sm SyntaxMap) Scanner {
+	return Scanner{lex: sm.Lex, skipped: sm.Skipped(), trivia: sm.Trivia(), r: r, buf: &strings.Builder{}, history: sm.History(), pos: Position{Line: 1, Col: 1}}
+}
+
+// Scanner reading the source from r with a lexer generated from a syntax map, generated
+// lexers have no anchors nor lookbehinds and need no history
+func NewLexerReaderScanner(r io.Reader, lex Lexer) Scanner {
+	return Scanner{lex: lex, skipped: map[Trait]bool{Blank: true}, trivia: map[Trait]bool{Comment: true}, r: r, buf: &strings.Builder{}, pos: Position{Line: 1, Col: 1}}
 }
 
+// Whether only blanks and comments are left, they lead the Eof token
 func (sn *Scanner) Finished() bool {
+	return sn.peek().Trait == Eof
+}
+
+func (sn *Scanner) exhausted() bool {
+	for sn.cur >= len(sn.src) && !sn.eof {
+		sn.read()
+	}
 	return sn.cur >= len(sn.src)
 }
 
+// Error of the reader other than io.EOF, the input ends where it failed
+func (sn *Scanner) Err() error {
+	return sn.err
+}
+
+// Next token without blanks, comments are kept as trivia of the neighboring tokens. The
+// comments following a token on its line trail it, the others lead the next token
 func (sn *Scanner) Tokenize() Token {
-	tok := sn.match()
+	tok := *sn.peek()
+	sn.next = nil
+
+	if tok.Ok && tok.Trait != NewLine {
+		tok.Trailing = sn.trail()
+	}
+	return tok
+}
 
-	if tok.Trait != Blank {
-		return tok
-	} else {
-		return sn.Tokenize()
+func (sn *Scanner) peek() *Token {
+	if sn.next == nil {
+		tok, leading := sn.match(), []Token(nil)
+		for sn.skipped[tok.Trait] || sn.trivia[tok.Trait] {
+			if sn.trivia[tok.Trait] {
+				leading = append(leading, tok)
+			}
+			tok = sn.match()
+		}
+		tok.Leading = leading
+		sn.next = &tok
+	}
+	return sn.next
+}
+
+// Comments up to the end of the line, the token ending them is kept for Tokenize. A
+// skipped token spanning a newline ends the line too
+func (sn *Scanner) trail() []Token {
+	var trailing []Token
+	for {
+		tok := sn.match()
+		switch {
+		case sn.skipped[tok.Trait] && strings.Contains(tok.Expr, "\n"):
+			return trailing
+		case sn.skipped[tok.Trait]:
+		case sn.trivia[tok.Trait]:
+			trailing = append(trailing, tok)
+		default:
+			sn.next = &tok
+			return trailing
+		}
 	}
 }
 
 func (sn *Scanner) match() Token {
-	if sn.Finished() {
-		return Token{len(sn.src) - 1, sn.src[len(sn.src)-1:], Eof, false}
+	if sn.exhausted() {
+		return Token{Pos: sn.Pos(), Trait: Eof}
+	}
+
+	trait, length, more := sn.lex(sn.src, sn.cur, sn.Longest, sn.eof)
+	for more {
+		sn.read()
+		trait, length, more = sn.lex(sn.src, sn.cur, sn.Longest, sn.eof)
+	}
+	// An empty token would not advance, it is no match
+	if length <= 0 {
+		trait, length = InvalidChar, sn.invalid()
 	}
+	pos, expr := sn.Pos(), sn.src[sn.cur:sn.cur+length]
+	pos.End += length
+	sn.advance(expr)
+	return Token{Pos: pos, Expr: expr, Trait: trait, Ok: true}
+}
 
-	for _, pt := range sn.smap {
-		match := pt.Regex.Match(sn.src[sn.cur:])
-		if match != -1 {
-			index := sn.cur
-			expr := sn.src[index : sn.cur+match]
-			sn.cur += match
-			return Token{index, expr, pt.Trait, true}
+// Length of the characters no pattern matches from cur, the scanner resumes at the
+// next character where a pattern matches a non-empty token
+func (sn *Scanner) invalid() int {
+	length := 0
+	for {
+		for !sn.eof && !utf8.FullRuneInString(sn.src[sn.cur+length:]) {
+			sn.read()
+		}
+		_, size := utf8.DecodeRuneInString(sn.src[sn.cur+length:])
+		length += size
+
+		for sn.cur+length >= len(sn.src) && !sn.eof {
+			sn.read()
+		}
+		if sn.cur+length >= len(sn.src) {
+			return length
+		}
+		_, match, more := sn.lex(sn.src, sn.cur+length, sn.Longest, sn.eof)
+		for more {
+			sn.read()
+			_, match, more = sn.lex(sn.src, sn.cur+length, sn.Longest, sn.eof)
+		}
+		if match > 0 {
+			return length
 		}
 	}
+}
 
-	return Token{0, `<unreachable>`, None, false}
+// Position of the next token, empty until End
+func (sn *Scanner) Pos() Position {
+	pos := sn.pos
+	pos.File, pos.End = sn.File, pos.Offset
+	return pos
+}
+
+// Moves cur past expr, lines and columns are counted on the scanned bytes only
+func (sn *Scanner) advance(expr string) {
+	sn.cur += len(expr)
+	sn.pos.Offset += len(expr)
+	if line := strings.LastIndexByte(expr, '\n'); line != -1 {
+		sn.pos.Line += strings.Count(expr, "\n")
+		sn.pos.Col = 1
+		expr = expr[line+1:]
+	}
+	sn.pos.Col += utf8.RuneCountInString(expr)
+}
+
+// Source line of the position without its newline, false when its beginning was
+// discarded by a reader
+func (sn *Scanner) Line(pos Position) (string, bool) {
+	offset := pos.Offset - sn.base
+	if offset < 0 || offset > len(sn.src) {
+		return "", false
+	}
+	begin := strings.LastIndexByte(sn.src[:offset], '\n') + 1
+	if begin == 0 && sn.base != 0 {
+		return "", false
+	}
+	end := strings.IndexByte(sn.src[offset:], '\n')
+	if end == -1 {
+		return sn.src[begin:], true
+	}
+	return sn.src[begin : offset+end], true
+}
+
+// Slides src past the scanned input and appends the next chunk of the reader, only the
+// bytes kept are copied
+func (sn *Scanner) read() {
+	if drop := sn.cur - sn.history; drop > 0 {
+		sn.buf = &strings.Builder{}
+		sn.buf.WriteString(sn.src[drop:])
+		sn.cur, sn.base = sn.cur-drop, sn.base+drop
+	}
+
+	chunk := make([]byte, scannerChunk)
+	n, err := sn.r.Read(chunk)
+	sn.buf.Write(chunk[:n])
+	sn.src = sn.buf.String()
+	if err != nil {
+		sn.eof = true
+		if err != io.EOF {
+			sn.err = err
+		}
+	}
 }
@@ -0,0 +1,612 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+var updateLexer = flag.Bool("update", false, "Regenerate bee_lexer.go from NewBeeSyntax")
+
+func expectTokens(ts *testing.T, sn Scanner, traits ...Trait) {
+	for _, trait := range traits {
+		if sn.Finished() {
+			ts.Logf(`Scanner finished before <%s>`, trait.Repr())
+			ts.Fail()
+			return
+		}
+		if tok := sn.Tokenize(); tok.Trait != trait {
+			ts.Logf(`Scanned <%s> "%s" instead of <%s>`, tok.Trait.Repr(), tok.Expr, trait.Repr())
+			ts.Fail()
+		}
+	}
+	if !sn.Finished() {
+		ts.Logf(`Scanner not finished after all tokens`)
+		ts.Fail()
+	}
+}
+
+// Pattern of the trait kept by the scanner, the test fails on an invalid regex
+func pattern(ts *testing.T, trait Trait, src string) Pattern {
+	rx, err := NewRegex(src)
+	if err != nil {
+		ts.Fatal(err)
+	}
+	return Pattern{trait, rx, KindKeep, nil}
+}
+
+func TestScannerLongest(ts *testing.T) {
+	// Shorter operators and identifiers first, the ordering NewBeeSyntax avoids
+	sm := SyntaxMap{
+		pattern(ts, Blank, `_+`),
+		pattern(ts, Identifier, `{a|'_'} {a|'_'|n}*`),
+		pattern(ts, KwIf, `'if'`),
+		pattern(ts, Less, `'<'`),
+		pattern(ts, LessEq, `'<='`),
+		pattern(ts, Define, `':'`),
+		pattern(ts, Declare, `'::'`),
+		pattern(ts, IntDec, `[0-9]+`),
+		pattern(ts, Float, `[0-9]+ '.' [0-9]*`),
+	}
+
+	sn := NewScanner("if iffy <= 1.5 :: x < 2", sm)
+	sn.Longest = true
+	expectTokens(ts, sn, Identifier, Identifier, LessEq, Float, Declare, Identifier, Less, IntDec)
+
+	sn = NewScanner("iffy ::", sm)
+	expectTokens(ts, sn, Identifier, Define, Define)
+}
+
+func TestScannerAnchors(ts *testing.T) {
+	// Directives only at the start of a line, other words after <#> are comments trailing
+	// the previous token
+	sm := SyntaxMap{
+		pattern(ts, NewLine, `'\n'`),
+		pattern(ts, Blank, `_+`),
+		pattern(ts, Directive, `< '#' a+`),
+		pattern(ts, Comment, `'#' a+`),
+		pattern(ts, KwIf, `% 'if' %`),
+		pattern(ts, Identifier, `a+`),
+	}
+
+	sn := NewScanner("#if x #note\n#end iffy if", sm)
+	expectTokens(ts, sn, Directive, Identifier, NewLine, Directive, Identifier, KwIf)
+}
+
+func TestScannerTrivia(ts *testing.T) {
+	src := "// leading\n#import /* a /* nested */ b */ x // trailing\n/* c */ y /* d */\n// end\n"
+	sn := NewScanner(src, NewBeeSyntax())
+	expectTokens(ts, sn, NewLine, Directive, Identifier, NewLine, Identifier, NewLine, NewLine)
+
+	trivia := func(toks []Token) []string {
+		exprs := make([]string, 0, len(toks))
+		for _, tok := range toks {
+			exprs = append(exprs, tok.Expr)
+		}
+		return exprs
+	}
+	expected := []struct {
+		expr     string
+		leading  []string
+		trailing []string
+	}{
+		{"\n", []string{"// leading"}, []string{}},
+		{"#import", []string{}, []string{"/* a /* nested */ b */"}},
+		{"x", []string{}, []string{"// trailing"}},
+		{"\n", []string{}, []string{}},
+		{"y", []string{"/* c */"}, []string{"/* d */"}},
+		{"\n", []string{}, []string{}},
+		{"\n", []string{"// end"}, []string{}},
+		{"", []string{}, []string{}},
+	}
+	sn = NewScanner(src, NewBeeSyntax())
+	for _, exp := range expected {
+		tok := sn.Tokenize()
+		leading, trailing := trivia(tok.Leading), trivia(tok.Trailing)
+		if tok.Expr != exp.expr || !reflect.DeepEqual(leading, exp.leading) || !reflect.DeepEqual(trailing, exp.trailing) {
+			ts.Logf(`Scanned "%s" with %q %q instead of "%s" with %q %q`, tok.Expr, leading, trailing, exp.expr, exp.leading, exp.trailing)
+			ts.Fail()
+		}
+	}
+
+	// Blanks and comments after the last token lead Eof
+	sn = NewScanner("x\n  /* end */ ", NewBeeSyntax())
+	expectTokens(ts, sn, Identifier, NewLine)
+	sn.Tokenize()
+	sn.Tokenize()
+	if tok := sn.Tokenize(); tok.Trait != Eof || len(tok.Leading) != 1 {
+		ts.Logf(`Scanned <%s> with %d comments instead of Eof led by the last comment`, tok.Trait.Repr(), len(tok.Leading))
+		ts.Fail()
+	}
+
+	// Blanks before a newline do not carry the comments of the next line
+	expected = []struct {
+		expr     string
+		leading  []string
+		trailing []string
+	}{
+		{"x", []string{}, []string{}},
+		{"\n", []string{}, []string{}},
+		{"\n", []string{"// c"}, []string{}},
+		{"y", []string{}, []string{}},
+	}
+	for _, src := range []string{"x\n// c\ny", "x \n// c\ny", "x\t \n// c\ny"} {
+		sn = NewScanner(src, NewBeeSyntax())
+		for _, exp := range expected {
+			tok := sn.Tokenize()
+			leading, trailing := trivia(tok.Leading), trivia(tok.Trailing)
+			if tok.Expr != exp.expr || !reflect.DeepEqual(leading, exp.leading) || !reflect.DeepEqual(trailing, exp.trailing) {
+				ts.Logf(`%q: Scanned "%s" with %q %q instead of "%s" with %q %q`, src, tok.Expr, leading, trailing, exp.expr, exp.leading, exp.trailing)
+				ts.Fail()
+			}
+		}
+	}
+
+	// Block comments nest to any depth
+	nested := "/* 1 /* 2 /* 3 /* 4 /* 5 */ */ */ */ */"
+	src = "x " + nested + " y"
+	for _, sn := range []Scanner{NewScanner(src, NewBeeSyntax()), NewLexerScanner(src, BeeLexer)} {
+		if tok := sn.Tokenize(); tok.Expr != "x" || !reflect.DeepEqual(trivia(tok.Trailing), []string{nested}) {
+			ts.Logf(`Scanned "%s" with %q instead of "x" with %q`, tok.Expr, trivia(tok.Trailing), nested)
+			ts.Fail()
+		}
+		expectTokens(ts, sn, Identifier)
+	}
+	expectTokens(ts, NewScanner("x "+nested[:len(nested)-3], NewBeeSyntax()), Identifier, UnterminatedComment)
+}
+
+func TestScannerErrors(ts *testing.T) {
+	sm := NewBeeSyntax()
+	expectTokens(ts, NewScanner("x @ 'abc\ny", sm), Identifier, InvalidChar, UnterminatedStr, NewLine, Identifier)
+	expectTokens(ts, NewScanner(`"raw`, sm), UnterminatedStr)
+	expectTokens(ts, NewScanner("`c", sm), UnterminatedChar)
+	expectTokens(ts, NewScanner("x /* a /* b */", sm), Identifier, UnterminatedComment)
+	expectTokens(ts, NewScanner("12ab 0b102 0x1f 0b1 0x", sm), MalformedNumber, MalformedNumber, IntHex, IntBin, MalformedNumber)
+	expectTokens(ts, NewScanner("é€ x", sm), InvalidChar, Identifier)
+
+	// Without a pattern matching, the scanner resumes at the next character a pattern
+	// matches and the run of characters before is one error
+	rx, _ := NewRegexMode("a+", ModeRunes)
+	expectTokens(ts, NewScanner("ab?é", SyntaxMap{{Identifier, rx, KindKeep, nil}}), Identifier, InvalidChar)
+	// Empty matches do not advance the scanner, they are no match
+	empty := SyntaxMap{pattern(ts, Blank, `_*`), pattern(ts, Identifier, `a+`)}
+	expectTokens(ts, NewScanner("x y", empty), InvalidChar, InvalidChar)
+
+	src := "x @é€@ y @"
+	expectReaderTokens(ts, "invalid", src, func(r io.Reader) (Scanner, Scanner) {
+		return NewScanner(src, sm), NewLexerReaderScanner(r, BeeLexer)
+	})
+	sn := NewScanner(src, sm)
+	for _, expr := range []string{"x", "@é€@", "y", "@"} {
+		if tok := sn.Tokenize(); tok.Expr != expr {
+			ts.Logf(`Scanned <%s> "%s" instead of "%s"`, tok.Trait.Repr(), tok.Expr, expr)
+			ts.Fail()
+		}
+	}
+
+	ps := NewParser("errors.bee", NewScanner("x : 1 @\n\ny : 2 'a\n\nz : 3 0b12\n\n", sm))
+	_, err := ps.Parse()
+	errs, ok := err.(ErrorList)
+	if !ok || len(errs) != 3 {
+		ts.Logf("Parsed with %v instead of 3 lexical errors", err)
+		ts.FailNow()
+	}
+	for i, reason := range []string{"Invalid character", "Unterminated string literal", "Malformed number literal"} {
+		lines := strings.Split(errs[i].Error(), "\n")
+		if !strings.HasSuffix(lines[1], "^ "+reason) || strings.Index(lines[1], "^") != strings.Index(lines[0], "> ")+8 {
+			ts.Logf("Reported %q instead of %s at the 7th column", errs[i].Error(), reason)
+			ts.Fail()
+		}
+	}
+
+	// The caret counts runes past the trimmed indentation
+	ps = NewParser("carets.bee", NewScanner("\t\ty :: \"é€\" @\n\n", sm))
+	_, err = ps.Parse()
+	if err == nil {
+		ts.Log("Parsed without the invalid character")
+		ts.FailNow()
+	}
+	lines := strings.Split(err.Error(), "\n")
+	if lines[0] != `from 'carets.bee':1 > y :: "é€" @` || lines[1] != strings.Repeat(" ", 32)+"^ Invalid character" {
+		ts.Logf("Reported %q with the caret off the '@'", err.Error())
+		ts.Fail()
+	}
+}
+
+func TestParserSpans(ts *testing.T) {
+	ps := NewParser("spans.bee", NewScanner("x :: 1 + 2\n\nif x == 3\n{\n\ty : x\n\n}\n", NewBeeSyntax()))
+	ast, err := ps.Parse()
+	if err != nil || len(ast.Body) != 2 {
+		ts.Logf("Parsed %v with %v instead of a declaration and a condition", ast, err)
+		ts.FailNow()
+	}
+	declare, _ := ast.Body[0].(DeclareExpr)
+	cond, _ := ast.Body[1].(If)
+	if declare.Expr == nil || len(cond.If.Body) != 1 {
+		ts.Logf("Parsed %#v instead of a declaration and a condition", ast.Body)
+		ts.FailNow()
+	}
+
+	spans := []struct {
+		node       Node
+		begin, end string
+	}{
+		{declare, "1:1", "1:10"},
+		{declare.Expr, "1:6", "1:10"},
+		{cond, "3:1", "7:1"},
+		{cond.Conds, "3:4", "4:1"},
+		{cond.If, "4:2", "7:1"},
+		{cond.If.Body[0], "5:2", "5:6"},
+	}
+	for _, span := range spans {
+		if src := span.node.Source(); src.Begin.String() != span.begin || src.End.String() != span.end {
+			ts.Logf("Spanned %T from %s to %s instead of %s to %s", span.node, src.Begin, src.End, span.begin, span.end)
+			ts.Fail()
+		}
+	}
+}
+
+func TestScannerPositions(ts *testing.T) {
+	sn := NewScanner("if x\n\ts := ü 1\n\nx", NewBeeSyntax())
+	sn.File = 2
+	positions := []Position{
+		{2, 1, 1, 0, 2}, {2, 1, 4, 3, 4}, {2, 1, 5, 4, 5},
+		{2, 2, 2, 6, 7}, {2, 2, 4, 8, 9}, {2, 2, 5, 9, 10}, {2, 2, 7, 11, 13}, {2, 2, 9, 14, 15}, {2, 2, 10, 15, 16},
+		{2, 3, 1, 16, 17}, {2, 4, 1, 17, 18}, {2, 4, 2, 18, 18},
+	}
+	for _, pos := range positions {
+		if tok := sn.Tokenize(); tok.Pos != pos {
+			ts.Logf(`Scanned <%s> "%s" at %+v instead of %+v`, tok.Trait.Repr(), tok.Expr, tok.Pos, pos)
+			ts.Fail()
+		}
+	}
+
+	lines := map[int]string{0: "if x", 4: "if x", 11: "\ts := ü 1", 16: "", 18: "x"}
+	for offset, line := range lines {
+		if got, ok := sn.Line(Position{Offset: offset}); !ok || got != line {
+			ts.Logf(`Line "%s" at %d instead of "%s"`, got, offset, line)
+			ts.Fail()
+		}
+	}
+}
+
+func TestScannerGrammar(ts *testing.T) {
+	file, err := os.Open("ideas/json.syntax")
+	if err != nil {
+		ts.Fatal(err)
+	}
+	defer file.Close()
+	sm, err := LoadSyntaxMap("ideas/json.syntax", file)
+	if err != nil {
+		ts.Fatal(err)
+	}
+
+	sn := NewScanner("{\"a\": [1, 2.5f], // c\n \"b\": x}\n", sm)
+	expected := []string{
+		"{ MapBegin", `"a" Str`, ": Colon", "[ ArrBegin", "1 Int", ", Comma", "2.5f Float", "] ArrEnd",
+		", Comma", `"b" Str`, ": Colon", "x Name", "} MapEnd",
+	}
+	for _, expr := range expected {
+		if tok := sn.Tokenize(); tok.Expr+" "+tok.Trait.Repr() != expr {
+			ts.Logf("Scanned %s %s instead of %s", tok.Expr, tok.Trait.Repr(), expr)
+			ts.Fail()
+		}
+	}
+	if !sn.Finished() {
+		ts.Log("Scanner is not finished after the last token")
+		ts.Fail()
+	}
+
+	// Comments are trivia by their kind, not by the name of a builtin trait
+	sn = NewScanner("x // c\n", sm)
+	if tok := sn.Tokenize(); len(tok.Trailing) != 1 || tok.Trailing[0].Trait != TraitNamed("Comment") {
+		ts.Logf("Scanned %s with %d comments instead of x trailed by // c", tok.Expr, len(tok.Trailing))
+		ts.Fail()
+	}
+	// Skipped blanks spanning a newline end the trailing comments
+	sn = NewScanner("x \n// c\ny", sm)
+	if tok := sn.Tokenize(); len(tok.Trailing) != 0 {
+		ts.Logf("Scanned %s trailed by %d comments of the next line", tok.Expr, len(tok.Trailing))
+		ts.Fail()
+	}
+	if tok := sn.Tokenize(); tok.Expr != "y" || len(tok.Leading) != 1 {
+		ts.Logf("Scanned %s with %d comments instead of y led by // c", tok.Expr, len(tok.Leading))
+		ts.Fail()
+	}
+
+	if TraitNamed("Str") == Str || TraitNamed("Comment") == Comment || TraitNamed("?") == None {
+		ts.Log("Grammar names are registered as builtin traits")
+		ts.Fail()
+	}
+	traits := make(chan Trait)
+	for i := 0; i < 8; i++ {
+		go func() { traits <- TraitNamed("Concurrent") }()
+	}
+	for i := 0; i < 8; i++ {
+		if trait := <-traits; trait != TraitNamed("Concurrent") || trait.Repr() != "Concurrent" {
+			ts.Log("Trait names are not registered once")
+			ts.Fail()
+		}
+	}
+
+	grammars := map[string]string{
+		"X keep\n":                            "grammar:1: Expected NAME KIND REGEX in <X keep>",
+		"// X keep 'x'\n\nX bogus 'x'\n":      "grammar:3: Unknown kind <bogus>, expected keep, skip, trivia or fragment",
+		"x fragment 'x'\nX keep <:x> <:y>\n":  "grammar:2: ",
+		"X keep [\n":                          "grammar:1: ",
+		"x fragment 'x'\nX skip <:x> | 'y'\n": "",
+		"X keep 'x'\nBlank skip _*\n":         "grammar:2: Pattern <_*> matches the empty input",
+	}
+	for src, expected := range grammars {
+		_, err := LoadSyntaxMap("grammar", strings.NewReader(src))
+		if (err == nil) != (expected == "") || (err != nil && !strings.HasPrefix(err.Error(), expected)) {
+			ts.Logf("Loaded %q with error %v instead of %q", src, err, expected)
+			ts.Fail()
+		}
+	}
+}
+
+func TestScannerGenerate(ts *testing.T) {
+	sm := NewBeeSyntax()
+	var buf bytes.Buffer
+	if err := sm.Generate(&buf, "main", "BeeLexer"); err != nil {
+		ts.Fatal(err)
+	}
+	if *updateLexer {
+		if err := os.WriteFile("bee_lexer.go", buf.Bytes(), 0644); err != nil {
+			ts.Fatal(err)
+		}
+	}
+	if src, _ := os.ReadFile("bee_lexer.go"); !bytes.Equal(src, buf.Bytes()) {
+		ts.Log("bee_lexer.go is not generated from NewBeeSyntax, run go test -run TestScannerGenerate -update")
+		ts.Fail()
+	}
+
+	files, _ := filepath.Glob("ideas/*")
+	for _, file := range files {
+		src, err := os.ReadFile(file)
+		if err != nil {
+			ts.Fatal(err)
+		}
+		for _, longest := range []bool{false, true} {
+			interpreted, generated := NewScanner(string(src), sm), NewLexerScanner(string(src), BeeLexer)
+			interpreted.Longest, generated.Longest = longest, longest
+
+			for !interpreted.Finished() {
+				tok, other := interpreted.Tokenize(), generated.Tokenize()
+				if !reflect.DeepEqual(tok, other) {
+					ts.Logf(`%s: Scanned <%s> "%s" at %d but the generated lexer scanned <%s> "%s" at %d`,
+						file, tok.Trait.Repr(), tok.Expr, tok.Pos.Offset, other.Trait.Repr(), other.Expr, other.Pos.Offset)
+					ts.Fail()
+					break
+				}
+				if !tok.Ok {
+					break
+				}
+			}
+		}
+	}
+
+	rx, err := NewRegex("< '#' a+")
+	if err != nil {
+		ts.Fatal(err)
+	}
+	anchored := SyntaxMap{{Directive, rx, KindKeep, nil}}
+	if err := anchored.Generate(&buf, "main", "AnchoredLexer"); err == nil {
+		ts.Log("Generated a lexer for a pattern without DFA")
+		ts.Fail()
+	}
+
+	// Builtin traits are written by name, grammar traits are registered by name
+	grammar, err := LoadSyntaxMap("grammar", strings.NewReader("Word keep a+\nDot keep '.'\n"))
+	if err != nil {
+		ts.Fatal(err)
+	}
+	buf.Reset()
+	if err := append(grammar, sm[0]).Generate(&buf, "main", "GrammarLexer"); err != nil {
+		ts.Fatal(err)
+	}
+	for _, entry := range []string{`{TraitNamed("Word"), grammarLexer0}`, `{TraitNamed("Dot"), grammarLexer1}`, "{NewLine, grammarLexer2}"} {
+		if !strings.Contains(buf.String(), entry) {
+			ts.Logf("Generated lexer without %s:\n%s", entry, buf.String())
+			ts.Fail()
+		}
+	}
+}
+
+// Scans src from a string and from readers, the tokens must be the same
+func expectReaderTokens(ts *testing.T, name string, src string, scanners func(io.Reader) (Scanner, Scanner)) {
+	readers := map[string]func() io.Reader{
+		"one byte": func() io.Reader { return iotest.OneByteReader(strings.NewReader(src)) },
+		"half":     func() io.Reader { return iotest.HalfReader(strings.NewReader(src)) },
+		"whole":    func() io.Reader { return strings.NewReader(src) },
+	}
+	for reader, r := range readers {
+		sn, streamed := scanners(r())
+		for !sn.Finished() {
+			tok, other := sn.Tokenize(), streamed.Tokenize()
+			if !reflect.DeepEqual(tok, other) {
+				ts.Logf(`%s: Scanned <%s> "%s" at %d but the %s reader scanned <%s> "%s" at %d`,
+					name, tok.Trait.Repr(), tok.Expr, tok.Pos.Offset, reader, other.Trait.Repr(), other.Expr, other.Pos.Offset)
+				ts.Fail()
+				break
+			}
+			if !tok.Ok {
+				break
+			}
+		}
+		if tok, other := sn.Tokenize(), streamed.Tokenize(); sn.Finished() && !reflect.DeepEqual(tok, other) {
+			ts.Logf(`%s: Ended at %d but the %s reader ended at %d`, name, tok.Pos.Offset, reader, other.Pos.Offset)
+			ts.Fail()
+		}
+	}
+}
+
+func TestScannerReader(ts *testing.T) {
+	sm := NewBeeSyntax()
+	files, _ := filepath.Glob("ideas/*")
+	for _, file := range files {
+		src, err := os.ReadFile(file)
+		if err != nil {
+			ts.Fatal(err)
+		}
+		for _, longest := range []bool{false, true} {
+			expectReaderTokens(ts, file, string(src), func(r io.Reader) (Scanner, Scanner) {
+				sn, streamed := NewScanner(string(src), sm), NewReaderScanner(r, sm)
+				sn.Longest, streamed.Longest = longest, longest
+				return sn, streamed
+			})
+			expectReaderTokens(ts, file, string(src), func(r io.Reader) (Scanner, Scanner) {
+				sn, streamed := NewScanner(string(src), sm), NewLexerReaderScanner(r, BeeLexer)
+				sn.Longest, streamed.Longest = longest, longest
+				return sn, streamed
+			})
+		}
+	}
+
+	// The anchors and lookbehinds read the discarded input
+	anchored := SyntaxMap{
+		pattern(ts, NewLine, `'\n'`),
+		pattern(ts, Blank, `_+`),
+		pattern(ts, Directive, `< '#' a+`),
+		pattern(ts, Comment, `'#' a+`),
+		pattern(ts, KwIf, `% 'if' %`),
+		pattern(ts, IntHex, `\{'0x'} [0-9a-f]+`),
+		pattern(ts, IntDec, `n+`),
+		pattern(ts, Identifier, `a+`),
+		pattern(ts, None, `^`),
+	}
+	src := strings.Repeat("#if x #note 0x1f 12\n#end iffy if\n", 300)
+	expectReaderTokens(ts, "anchored", src, func(r io.Reader) (Scanner, Scanner) {
+		return NewScanner(src, anchored), NewReaderScanner(r, anchored)
+	})
+
+	// Unbounded lookbehinds keep the whole input, the chunks are appended without copying it
+	unbounded := SyntaxMap{
+		pattern(ts, Blank, `_+`),
+		pattern(ts, Directive, `'@' \{'#' a*}`),
+		pattern(ts, IntDec, `n+`),
+		pattern(ts, Identifier, `a+`),
+	}
+	if history := unbounded.History(); history != math.MaxInt {
+		ts.Logf("History of %d bytes for an unbounded lookbehind", history)
+		ts.Fail()
+	}
+	src = strings.Repeat("x 12 ", 50000)
+	sn := NewReaderScanner(iotest.OneByteReader(strings.NewReader(src)), unbounded)
+	for i := 0; !sn.Finished(); i++ {
+		if tok := sn.Tokenize(); i%2 == 1 && tok.Trait != IntDec {
+			ts.Logf(`Scanned <%s> "%s" at %d`, tok.Trait.Repr(), tok.Expr, tok.Pos.Offset)
+			ts.Fail()
+			break
+		}
+	}
+
+	failure := errors.New("failure")
+	sn = NewReaderScanner(io.MultiReader(strings.NewReader("if x"), iotest.ErrReader(failure)), NewBeeSyntax())
+	for !sn.Finished() {
+		sn.Tokenize()
+	}
+	if err := sn.Err(); err != failure {
+		ts.Logf("Reader error %v instead of %v", err, failure)
+		ts.Fail()
+	}
+	sn = NewReaderScanner(strings.NewReader(""), sm)
+	if tok := sn.Tokenize(); tok.Trait != Eof || tok.Pos.Offset != 0 {
+		ts.Logf(`Scanned <%s> at %d from an empty reader`, tok.Trait.Repr(), tok.Pos.Offset)
+		ts.Fail()
+	}
+}
+
+func TestScannerKeywords(ts *testing.T) {
+	src := "fn fnord or and x"
+	expectTokens(ts, NewScanner(src, NewBeeSyntax()), KwFn, Identifier, KwOr, KwAnd, Identifier)
+	expectTokens(ts, NewLexerScanner(src, BeeLexer), KwFn, Identifier, KwOr, KwAnd, Identifier)
+
+	reprs := map[Trait]string{Declare: "::", Define: ":", Mod: "%", RawStr: "RawStr"}
+	for trait, repr := range reprs {
+		if trait.Repr() != repr {
+			ts.Logf(`<%s> instead of <%s>`, trait.Repr(), repr)
+			ts.Fail()
+		}
+	}
+}
+
+func TestScannerTokensCommand(ts *testing.T) {
+	file := filepath.Join(ts.TempDir(), "tokens.bee")
+	if err := os.WriteFile(file, []byte("// lead\nfoo :: 12 // trail\n@"), 0644); err != nil {
+		ts.Fatal(err)
+	}
+	empty := filepath.Join(ts.TempDir(), "empty.syntax")
+	if err := os.WriteFile(empty, []byte("Blank skip _*\nWord keep a+\n"), 0644); err != nil {
+		ts.Fatal(err)
+	}
+	operators := filepath.Join(ts.TempDir(), "operators.bee")
+	if err := os.WriteFile(operators, []byte("a<=b&c"), 0644); err != nil {
+		ts.Fatal(err)
+	}
+
+	commands := []struct {
+		args   []string
+		code   int
+		stdout string
+	}{
+		{[]string{file}, exitOk, `"\n"   \n           1:8
+"foo"  Identifier   2:1
+"::"   ::           2:5
+"12"   IntDec       2:8
+"\n"   \n           2:19
+"@"    InvalidChar  3:1
+`},
+		{[]string{"-trivia", file}, exitOk, `"// lead"   Comment      1:1   leading
+"\n"        \n           1:8
+"foo"       Identifier   2:1
+"::"        ::           2:5
+"12"        IntDec       2:8
+"// trail"  Comment      2:11  trailing
+"\n"        \n           2:19
+"@"         InvalidChar  3:1
+`},
+		{[]string{"-json", "-trivia", file}, exitOk, `{"expr":"// lead","trait":"Comment","line":1,"col":1,"offset":0,"end":7,"trivia":"leading"}
+{"expr":"\n","trait":"\\n","line":1,"col":8,"offset":7,"end":8}
+{"expr":"foo","trait":"Identifier","line":2,"col":1,"offset":8,"end":11}
+{"expr":"::","trait":"::","line":2,"col":5,"offset":12,"end":14}
+{"expr":"12","trait":"IntDec","line":2,"col":8,"offset":15,"end":17}
+{"expr":"// trail","trait":"Comment","line":2,"col":11,"offset":18,"end":26,"trivia":"trailing"}
+{"expr":"\n","trait":"\\n","line":2,"col":19,"offset":26,"end":27}
+{"expr":"@","trait":"InvalidChar","line":3,"col":1,"offset":27,"end":28}
+`},
+		{[]string{"-json", operators}, exitOk, `{"expr":"a","trait":"Identifier","line":1,"col":1,"offset":0,"end":1}
+{"expr":"<=","trait":"<=","line":1,"col":2,"offset":1,"end":3}
+{"expr":"b","trait":"Identifier","line":1,"col":4,"offset":3,"end":4}
+{"expr":"&","trait":"&","line":1,"col":5,"offset":4,"end":5}
+{"expr":"c","trait":"Identifier","line":1,"col":6,"offset":5,"end":6}
+`},
+		{[]string{"-grammar", "ideas/json.syntax", file}, exitOk, `"foo"  Name         2:1
+":"    Colon        2:5
+":"    Colon        2:6
+"12"   Int          2:8
+"@"    InvalidChar  3:1
+`},
+		{[]string{"-grammar", filepath.Join(ts.TempDir(), "missing.syntax"), file}, exitFailure, ""},
+		{[]string{"-grammar", empty, file}, exitFailure, ""},
+		{[]string{"-json", filepath.Join(ts.TempDir(), "missing.bee")}, exitFailure, ""},
+		{[]string{"-color", file}, exitUsage, ""},
+		{[]string{}, exitUsage, ""},
+	}
+	for _, cmd := range commands {
+		var stdout, stderr bytes.Buffer
+		if code := tokensCommand(cmd.args, &stdout, &stderr); code != cmd.code || stdout.String() != cmd.stdout {
+			ts.Logf("bee tokens %s exited with %d instead of %d:\n%s", strings.Join(cmd.args, " "), code, cmd.code, stdout.String())
+			ts.Fail()
+		}
+	}
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"sort"
+	"unicode/utf8"
+)
+
+// Rewrites of the node graph keeping the outcome of node.Submit. Edges stay sorted by
+// index, so a rewrite is only applied when the order of the edges is kept
+
+// Copy of the regex with a simplified graph
+func (rx *Regex) Simplify() Regex {
+	simple := *rx
+	if rx.Head != nil {
+		simple.Head = rx.Head.Clone().Simplify()
+		simple.compile()
+	}
+	return simple
+}
+
+// Removes epsilon chains, merges alternations of single characters into a scope and
+// collapses concatenated texts, the graph is modified in place
+func (n *node) Simplify() *node {
+	head := n
+	for changed := true; changed; {
+		changed = false
+		membs := head.unique()
+		preds := make(map[*node]nodes)
+
+		for _, member := range membs {
+			if seq := member.state.seq; seq != nil {
+				member.state.seq = seq.Simplify()
+			}
+			member.edges = member.edges.unique()
+			for _, edge := range member.edges {
+				preds[edge] = append(preds[edge], member)
+			}
+		}
+
+		for _, member := range membs {
+			if member.mergeScopes(preds) || member.spliceEpsilons(preds) || member.collapseText(preds) {
+				changed = true
+				break
+			}
+		}
+
+		if !changed && head.state.Tag == epsilon && len(head.edges) == 1 && head.Branch() && len(preds[head]) == 0 {
+			head, changed = head.edges[0], true
+		}
+	}
+	return head
+}
+
+// Members without duplicates
+func (n *node) unique() nodes {
+	return n.Membs().unique()
+}
+
+func (s nodes) unique() nodes {
+	seen := make(map[*node]bool, len(s))
+	unique := make(nodes, 0, len(s))
+	for _, n := range s {
+		if !seen[n] {
+			seen[n] = true
+			unique = append(unique, n)
+		}
+	}
+	return unique
+}
+
+func (s nodes) equal(other nodes) bool {
+	if len(s) != len(other) {
+		return false
+	}
+	for i := range s {
+		if s[i] != other[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// {[0-9]|[a-f]} into [0-9a-f] when both alternatives continue with the same edges
+func (n *node) mergeScopes(preds map[*node]nodes) bool {
+	if n.state.Tag != epsilon {
+		return false
+	}
+
+	for i := 0; i+1 < len(n.edges); i++ {
+		a, b := n.edges[i], n.edges[i+1]
+		if len(preds[a]) != 1 || len(preds[b]) != 1 || !a.edges.equal(b.edges) || a.state.runes != b.state.runes || a.state.flags != b.state.flags {
+			continue
+		}
+		ra, okA := a.state.charRanges()
+		rb, okB := b.state.charRanges()
+		if !okA || !okB {
+			continue
+		}
+
+		a.state = newScope(mergeRanges(append(ra, rb...)), false)
+		a.state.runes, a.state.flags = b.state.runes, b.state.flags
+		n.edges = append(n.edges[:i+1], n.edges[i+2:]...)
+		return true
+	}
+	return false
+}
+
+// Ranges of a state consuming a single character among a fixed set
+func (s *state) charRanges() ([][2]rune, bool) {
+	ranges := make([][2]rune, 0, 4)
+
+	switch s.Tag {
+	case text:
+		if len(s.str) == 0 {
+			return nil, false
+		}
+		c, size := rune(s.str[0]), 1
+		if s.runes {
+			c, size = utf8.DecodeRuneInString(s.str)
+		}
+		if len(s.str) != size || c == utf8.RuneError {
+			return nil, false
+		}
+		return append(ranges, [2]rune{c, c}), true
+
+	case set:
+		for i := 0; i < len(s.str); {
+			c, size := rune(s.str[i]), 1
+			if s.runes {
+				c, size = utf8.DecodeRuneInString(s.str[i:])
+			}
+			ranges = append(ranges, [2]rune{c, c})
+			i += size
+		}
+		return ranges, len(ranges) != 0
+
+	case scope:
+		if s.neg {
+			return nil, false
+		}
+		return append(ranges, s.ranges...), true
+	}
+
+	return nil, false
+}
+
+// Sorted ranges where overlapping and contiguous ranges are joined
+func mergeRanges(ranges [][2]rune) [][2]rune {
+	sort.Slice(ranges, func(i, j int) bool {
+		return ranges[i][0] < ranges[j][0]
+	})
+
+	merged := make([][2]rune, 0, len(ranges))
+	for _, r := range ranges {
+		if last := len(merged) - 1; last >= 0 && r[0] <= merged[last][1]+1 {
+			if r[1] > merged[last][1] {
+				merged[last][1] = r[1]
+			}
+			continue
+		}
+		merged = append(merged, r)
+	}
+	return merged
+}
+
+// Replaces the edge to an epsilon node only entered from n by the edges of the epsilon
+// node. Loop heads and join points are kept
+func (n *node) spliceEpsilons(preds map[*node]nodes) bool {
+	for i, e := range n.edges {
+		if e == n || e.state.Tag != epsilon || e.index <= n.index || !e.Branch() || len(preds[e]) != 1 {
+			continue
+		}
+		loop := false
+		for _, edge := range e.edges {
+			loop = loop || edge.index <= e.index
+		}
+		if loop {
+			continue
+		}
+
+		edges := make(nodes, 0, len(n.edges)+len(e.edges))
+		edges = append(edges, n.edges[:i]...)
+		edges = append(edges, e.edges...)
+		edges = append(edges, n.edges[i+1:]...)
+		edges = edges.unique()
+
+		if !sort.SliceIsSorted(edges, func(i, j int) bool { return edges[i].index < edges[j].index }) {
+			continue
+		}
+		n.edges = edges
+		return true
+	}
+	return false
+}
+
+// 'ab' 'cd' into 'abcd' when the second text is only entered from the first
+func (n *node) collapseText(preds map[*node]nodes) bool {
+	if n.state.Tag != text || len(n.edges) != 1 {
+		return false
+	}
+
+	next := n.edges[0]
+	if next == n || next.state.Tag != text || next.index <= n.index || len(preds[next]) != 1 || next.state.flags != n.state.flags {
+		return false
+	}
+	for _, edge := range next.edges {
+		if edge.index > n.index && edge.index <= next.index {
+			return false
+		}
+	}
+
+	n.state.str += next.state.str
+	n.edges = next.edges
+	return true
+}
@@ -1,26 +1,212 @@
 package main
 
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
 type Pattern struct {
 	Trait Trait
 	Regex Regex
+	Kind  PatternKind
+	// Blocks matched instead of the regex, nil for a regex pattern
+	Nest *Nesting
+}
+
+// How the scanner hands out the tokens of a pattern
+type PatternKind uint
+
+const (
+	// Tokens returned by Scanner.Tokenize
+	KindKeep PatternKind = iota
+	// Tokens dropped like blanks
+	KindSkip
+	// Tokens kept as trivia of the neighboring tokens like comments
+	KindTrivia
+)
+
+// Blocks between delimiters nesting to any depth, such as /* a /* b */ */. A regex
+// cannot count the depth, the openers and closers are counted instead
+type Nesting struct {
+	Open  string
+	Close string
+}
+
+// Length of the block at the index of src, -1 unless it is closed. Unless eof, more tells
+// that the block depends on the input following src
+func (nt Nesting) MatchMore(src string, index int, eof bool) (int, bool) {
+	src = src[index:]
+	if !strings.HasPrefix(src, nt.Open) {
+		return -1, !eof && strings.HasPrefix(nt.Open, src)
+	}
+	depth := 0
+	for i := 0; i < len(src); {
+		switch {
+		case strings.HasPrefix(src[i:], nt.Open):
+			depth, i = depth+1, i+len(nt.Open)
+		case strings.HasPrefix(src[i:], nt.Close):
+			depth, i = depth-1, i+len(nt.Close)
+			if depth == 0 {
+				return i, false
+			}
+		default:
+			i++
+		}
+	}
+	return -1, !eof
 }
 
 type SyntaxMap []Pattern
 
+func (sm SyntaxMap) Lex(src string, index int, longest bool, eof bool) (Trait, int, bool) {
+	trait, length := None, -1
+	for _, pt := range sm {
+		var match int
+		var more bool
+		if pt.Nest != nil {
+			match, more = pt.Nest.MatchMore(src, index, eof)
+		} else {
+			match, more = pt.Regex.MatchMore(src, index, eof)
+		}
+		if more {
+			return None, -1, true
+		}
+		if match > length {
+			trait, length = pt.Trait, match
+			if !longest {
+				break
+			}
+		}
+	}
+	return trait, length, false
+}
+
+// Bytes before the index read by the patterns, kept by a Scanner reading from an
+// io.Reader when it discards the scanned input. Lookbehinds of unbounded width such as
+// \{a+} read the whole input before the index and give math.MaxInt
+func (sm SyntaxMap) History() int {
+	history := 0
+	for _, pt := range sm {
+		if pt.Regex.Head == nil {
+			continue
+		}
+		if width := pt.Regex.Head.BehindWidth(); width > history {
+			history = width
+		}
+	}
+	return history
+}
+
+// Traits whose tokens the scanner drops, Blank is always dropped
+func (sm SyntaxMap) Skipped() map[Trait]bool {
+	return sm.traitsOf(KindSkip, Blank)
+}
+
+// Traits whose tokens the scanner keeps as trivia, Comment is always trivia
+func (sm SyntaxMap) Trivia() map[Trait]bool {
+	return sm.traitsOf(KindTrivia, Comment)
+}
+
+func (sm SyntaxMap) traitsOf(kind PatternKind, builtin Trait) map[Trait]bool {
+	traits := map[Trait]bool{builtin: true}
+	for _, pt := range sm {
+		if pt.Kind == kind {
+			traits[pt.Trait] = true
+		}
+	}
+	return traits
+}
+
+// Syntax map of a grammar, one definition per line as NAME KIND REGEX where the kind is
+// keep, skip or trivia for a pattern of the trait named NAME, or fragment for a regex
+// referenced as <:NAME> by the definitions below. The regex is the rest of the line,
+// patterns are tried in order. Blank lines and lines starting with // are skipped. The
+// traits are registered by TraitNamed, apart from the builtin traits of the same name.
+// Patterns matching the empty input are rejected
+//
+//	Example: Int keep [0-9]+
+func LoadSyntaxMap(name string, r io.Reader) (SyntaxMap, error) {
+	sm := SyntaxMap{}
+	fragments := NewFragments()
+
+	sc := bufio.NewScanner(r)
+	for line := 1; sc.Scan(); line++ {
+		text := strings.TrimSpace(sc.Text())
+		if text == "" || strings.HasPrefix(text, "//") {
+			continue
+		}
+
+		id, rest := cutField(text)
+		kind, src := cutField(rest)
+		if id == "" || kind == "" || src == "" {
+			return nil, fmt.Errorf("%s:%d: Expected NAME KIND REGEX in <%s>", name, line, text)
+		}
+
+		switch kind {
+		case "fragment":
+			if err := fragments.Define(id, src); err != nil {
+				return nil, fmt.Errorf("%s:%d: %w", name, line, err)
+			}
+		case "keep", "skip", "trivia":
+			rx, err := NewRegexFragments(src, ModeDfa, fragments)
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: %w", name, line, err)
+			}
+			if rx.Match("") != -1 {
+				return nil, fmt.Errorf("%s:%d: Pattern <%s> matches the empty input, its tokens would not advance the scanner", name, line, src)
+			}
+			sm = append(sm, Pattern{TraitNamed(id), rx, patternKinds[kind], nil})
+		default:
+			return nil, fmt.Errorf("%s:%d: Unknown kind <%s>, expected keep, skip, trivia or fragment", name, line, kind)
+		}
+	}
+	return sm, sc.Err()
+}
+
+var patternKinds = map[string]PatternKind{"keep": KindKeep, "skip": KindSkip, "trivia": KindTrivia}
+
+// First blank-separated field of str and the rest without leading blanks
+func cutField(str string) (string, string) {
+	end := strings.IndexAny(str, " \t")
+	if end == -1 {
+		return str, ""
+	}
+	return str[:end], strings.TrimLeft(str[end:], " \t")
+}
+
+// The lexer of bee_lexer.go is generated from this syntax map, run
+// go test -run TestScannerGenerate -update after changing it
 func NewBeeSyntax() SyntaxMap {
+	fragments := NewFragments()
+	frag := func(name string, src string) {
+		if err := fragments.Define(name, src); err != nil {
+			panic(err)
+		}
+	}
 	def := func(trait Trait, src string) Pattern {
-		rx, err := NewRegex(src)
+		rx, err := NewRegexFragments(src, ModeDfa, fragments)
 		if err != nil {
 			panic(err)
 		}
-		return Pattern{trait, rx}
+		return Pattern{trait, rx, KindKeep, nil}
+	}
+	nest := func(trait Trait, open, close string) Pattern {
+		return Pattern{trait, Regex{}, KindKeep, &Nesting{open, close}}
 	}
 
+	frag("hexdigit", `[0-9a-fA-F]`)
+	frag("idhead", `a|'_'`)
+	frag("escaped", `'\\'^`)
+
 	return SyntaxMap{
-		def(NewLine, "'\n'"),
-		def(Blank, `_+`),
-		//def(Comment, `'//' {^  ~ '\n'}'`),
-		//def(Directive, `'#' {^  ~ '\n'}'`),
+		def(NewLine, `'\n'`),
+		def(Blank, `{/!'\n' _}+`),
+		def(Comment, `'//' {!'\n'}*`),
+		nest(Comment, "/*", "*/"),
+		def(UnterminatedComment, `'/*' ^*`),
+		def(Directive, `'#' <:idhead> {<:idhead>|n}*`),
 
 		def(KwStruct, `'struct'/!a`),
 		def(KwEnum, `'enum'/!a`),
@@ -38,7 +224,7 @@ func NewBeeSyntax() SyntaxMap {
 		def(KwSwitch, `'switch'/!a`),
 		def(KwAnd, `'and'/!a`),
 		def(KwOr, `'or'/!a`),
-		def(KwOr, `'fn'/!a`),
+		def(KwFn, `'fn'/!a`),
 
 		def(ParenBegin, `'('`),
 		def(ParenEnd, `')'`),
@@ -53,15 +239,20 @@ func NewBeeSyntax() SyntaxMap {
 		def(Add, `'+'`),
 		def(Sub, `'-'`),
 
+		def(MalformedNumber, `'0b' [0-1]* [2-9] {<:idhead>|n}*`),
+		def(IntBin, `'0b' [0-1]+`),
+		def(IntHex, `'0x' <:hexdigit>+`),
+		def(MalformedNumber, `n+ <:idhead> {<:idhead>|n}*`),
 		def(Float, `{[0-9]+ '.' [0-9]*} | {[0-9]* '.' [0-9]+}`),
 		def(IntDec, `[0-9]+`),
-		def(IntBin, `'0b' [0-1]+`),
-		def(IntHex, `'0x' {[0-9]|[a-f]|[A-F]}+`),
 
-		def(RawStr, "Q^Q"),
-		def(Str, "q {{{'\\'^}|^} ~ /{q|'\n'}} ? {q|'\n'}"),
-		def(Char, "'`' {{{'\\'^}|^} ~ /{'`'|'\n'}} ? {'`'|'\n'}"),
-		def(Identifier, `{a|'_'} {a|'_'|n}*`),
+		def(RawStr, `Q {!Q}* Q`),
+		def(UnterminatedStr, `Q {!Q}*`),
+		def(Str, `q {<:escaped>|!{q|'\n'}}* q`),
+		def(UnterminatedStr, `q {<:escaped>|!{q|'\n'}}*`),
+		def(Char, `'\x60' {<:escaped>|!{'\x60'|'\n'}}* '\x60'`),
+		def(UnterminatedChar, `'\x60' {<:escaped>|!{'\x60'|'\n'}}*`),
+		def(Identifier, `<:idhead> {<:idhead>|n}*`),
 
 		def(Declare, `'::'`),
 		def(Define, `':'`),
@@ -85,7 +276,5 @@ func NewBeeSyntax() SyntaxMap {
 		def(Dot, `'.'`),
 		def(Comma, `','`),
 		def(Semicolon, `';'`),
-
-		def(None, `^~_`),
 	}
 }
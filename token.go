@@ -1,8 +1,27 @@
 package main
 
+import "fmt"
+
+// Location of a token in a source file, lines and columns start at 1 and columns count
+// runes. Offset and End delimit the token bytes in the whole input
+type Position struct {
+	File   int
+	Line   int
+	Col    int
+	Offset int
+	End    int
+}
+
 type Token struct {
-	Index int
+	Pos   Position
 	Expr  string
 	Trait Trait
 	Ok    bool
+	// Comments before the token, and after it on the same line
+	Leading  []Token
+	Trailing []Token
+}
+
+func (pos Position) String() string {
+	return fmt.Sprintf("%d:%d", pos.Line, pos.Col)
 }
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"encoding/json"
+)
+
+// Node submitted at an index while tracing a match. Nodes are numbered as in
+// node.Numbered so traces of the same pattern can be compared
+type TraceStep struct {
+	Node  int    `json:"node"`
+	State string `json:"state"`
+	Index int    `json:"index"`
+	// End of the match from the node, -1 on failure
+	Match int `json:"match"`
+	// Step submitting the node through an edge or a sequence, -1 for the head
+	Parent int `json:"parent"`
+	// Outcome already known for the (node, index) pair
+	Cached bool `json:"cached,omitempty"`
+	// Submitted inside the sequence of a not, dash or behind state
+	Nested bool `json:"nested,omitempty"`
+}
+
+type RegexTrace struct {
+	Src   string      `json:"src"`
+	Expr  string      `json:"expr"`
+	Match int         `json:"match"`
+	Steps []TraceStep `json:"steps"`
+	// Steps along the matching path, or leading to the failing step without a match
+	Path []int `json:"path"`
+	// Furthest step whose state did not match, -1 when expr is matched
+	Fail int `json:"fail"`
+}
+
+// Records every step of the memoized simulation of node.Submit on expr, the first
+// successful path wins whatever the mode of the regex
+func (rx *Regex) Trace(expr string) RegexTrace {
+	t := RegexTrace{
+		Src:   rx.Src,
+		Expr:  expr,
+		Match: -1,
+		Steps: make([]TraceStep, 0, 64),
+		Path:  make([]int, 0, 16),
+		Fail:  -1,
+	}
+	if rx.Head == nil {
+		return t
+	}
+
+	var rg RegexGraph
+	numbered := rx.Head.Numbered()
+	ids := make(map[*node]int, len(numbered))
+	for id, n := range numbered {
+		ids[n] = id
+	}
+
+	m := make(memo)
+	first := make(map[memoKey]int)
+	parents := make([]int, 0, 16)
+
+	var submit func(n *node, expr string, index int) int
+	submit = func(n *node, expr string, index int) int {
+		step := TraceStep{Node: ids[n], State: rg.makeState(n), Index: index, Match: -1, Parent: -1}
+		if len(parents) != 0 {
+			step.Parent = parents[len(parents)-1]
+			parent := &t.Steps[step.Parent]
+			step.Nested = parent.Nested || numbered[parent.Node].state.seq == n
+		}
+
+		key := memoKey{n, index}
+		if match, found := m[key]; found {
+			step.Match, step.Cached = match, true
+			t.Steps = append(t.Steps, step)
+			return match
+		}
+
+		id := len(t.Steps)
+		t.Steps = append(t.Steps, step)
+		first[key] = id
+
+		m[key] = -1
+		parents = append(parents, id)
+		match := n.submit(expr, index, submit)
+		parents = parents[:len(parents)-1]
+		m[key] = match
+
+		t.Steps[id].Match = match
+		return match
+	}
+	t.Match = submit(rx.Head, expr, 0)
+
+	if t.Match != -1 {
+		firstPath := *rx
+		firstPath.Mode &^= ModeLongest
+		for _, key := range firstPath.path(expr, m) {
+			if id, found := first[key]; found {
+				t.Path = append(t.Path, id)
+			}
+		}
+		return t
+	}
+
+	for id, step := range t.Steps {
+		if step.Cached || step.Nested || step.Index < t.stepIndex(t.Fail) {
+			continue
+		}
+		if numbered[step.Node].state.submit(expr, step.Index, m.Submit) == -1 {
+			t.Fail = id
+		}
+	}
+	for id := t.Fail; id != -1; id = t.Steps[id].Parent {
+		t.Path = append([]int{id}, t.Path...)
+	}
+	return t
+}
+
+func (t *RegexTrace) stepIndex(id int) int {
+	if id == -1 {
+		return -1
+	}
+	return t.Steps[id].Index
+}
+
+func (t *RegexTrace) Json() ([]byte, error) {
+	return json.MarshalIndent(t, "", "	")
+}
+
+// Nodes of the graph in depth first order without duplicates, the nodes of the
+// sequence of a not, dash or behind state follow its node
+func (n *node) Numbered() nodes {
+	numbered := make(nodes, 0, 32)
+	seen := make(map[*node]bool)
+
+	var number func(n *node)
+	number = func(n *node) {
+		for _, member := range n.Membs() {
+			if seen[member] {
+				continue
+			}
+			seen[member] = true
+			numbered = append(numbered, member)
+			if member.state.seq != nil {
+				number(member.state.seq)
+			}
+		}
+	}
+	number(n)
+
+	return numbered
+}
@@ -1,5 +1,7 @@
 package main
 
+import "sync"
+
 type Trait uint
 
 const (
@@ -12,6 +14,13 @@ const (
 	Comment
 	Directive
 
+	// Lexical errors, scanned up to the point where scanning can resume
+	InvalidChar
+	UnterminatedStr
+	UnterminatedChar
+	UnterminatedComment
+	MalformedNumber
+
 	KwStruct
 	KwEnum
 	KwUnion
@@ -78,6 +87,51 @@ const (
 	Semicolon
 )
 
+// Go names of the builtin traits, written by the generated lexers
+var traitIdents = [Semicolon + 1]string{
+	None: "None", NewLine: "NewLine", Empty: "Empty", Blank: "Blank", Eof: "Eof",
+	Comment: "Comment", Directive: "Directive", InvalidChar: "InvalidChar",
+	UnterminatedStr: "UnterminatedStr", UnterminatedChar: "UnterminatedChar",
+	UnterminatedComment: "UnterminatedComment", MalformedNumber: "MalformedNumber",
+	KwStruct: "KwStruct", KwEnum: "KwEnum", KwUnion: "KwUnion",
+	KwUnderscore: "KwUnderscore", KwSelf: "KwSelf", KwArrow: "KwArrow", KwBreak: "KwBreak",
+	KwCase: "KwCase", KwContinue: "KwContinue", KwElse: "KwElse", KwEach: "KwEach",
+	KwFor: "KwFor", KwIf: "KwIf", KwReturn: "KwReturn", KwSwitch: "KwSwitch",
+	KwAnd: "KwAnd", KwOr: "KwOr", KwFn: "KwFn", Identifier: "Identifier", Float: "Float",
+	IntDec: "IntDec", IntBin: "IntBin", IntHex: "IntHex", Str: "Str", RawStr: "RawStr",
+	Char: "Char", Increment: "Increment", Decrement: "Decrement", ParenBegin: "ParenBegin",
+	ParenEnd: "ParenEnd", ScopeBegin: "ScopeBegin", ScopeEnd: "ScopeEnd",
+	CrochetBegin: "CrochetBegin", CrochetEnd: "CrochetEnd", Declare: "Declare",
+	Define: "Define", Assign: "Assign", Arrow: "Arrow", Not: "Not", Add: "Add", Sub: "Sub",
+	Mul: "Mul", Div: "Div", Mod: "Mod", BinNot: "BinNot", BinAnd: "BinAnd", BinOr: "BinOr",
+	BinXor: "BinXor", BinShiftL: "BinShiftL", BinShiftR: "BinShiftR", Equal: "Equal",
+	NotEq: "NotEq", Less: "Less", Greater: "Greater", LessEq: "LessEq",
+	GreaterEq: "GreaterEq", Ref: "Ref", Deref: "Deref", Dot: "Dot", Comma: "Comma",
+	Semicolon: "Semicolon",
+}
+
+// Traits registered at runtime by name, numbered after the builtin traits. Grammars may
+// be loaded concurrently, the registry is locked
+var (
+	traitsLock    sync.RWMutex
+	traitNames    []string
+	traitsByNames = make(map[string]Trait)
+)
+
+// Trait of a grammar name registered on first use. The registered traits are apart from
+// the builtin ones, a grammar naming a trait Comment or Eof gets its own
+func TraitNamed(name string) Trait {
+	traitsLock.Lock()
+	defer traitsLock.Unlock()
+	if trait, found := traitsByNames[name]; found {
+		return trait
+	}
+	trait := Semicolon + 1 + Trait(len(traitNames))
+	traitNames = append(traitNames, name)
+	traitsByNames[name] = trait
+	return trait
+}
+
 func (trait Trait) Repr() string {
 	switch trait {
 
@@ -93,6 +147,16 @@ func (trait Trait) Repr() string {
 		return "Comment"
 	case Directive:
 		return "Directive"
+	case InvalidChar:
+		return "InvalidChar"
+	case UnterminatedStr:
+		return "UnterminatedStr"
+	case UnterminatedChar:
+		return "UnterminatedChar"
+	case UnterminatedComment:
+		return "UnterminatedComment"
+	case MalformedNumber:
+		return "MalformedNumber"
 
 	case KwStruct:
 		return "Struct"
@@ -144,6 +208,8 @@ func (trait Trait) Repr() string {
 		return "IntHex"
 	case Str:
 		return "Str"
+	case RawStr:
+		return "RawStr"
 	case Char:
 		return "Char"
 
@@ -164,9 +230,9 @@ func (trait Trait) Repr() string {
 	case CrochetEnd:
 		return "]"
 	case Declare:
-		return ":"
-	case Define:
 		return "::"
+	case Define:
+		return ":"
 	case Assign:
 		return "="
 	case Arrow:
@@ -182,7 +248,7 @@ func (trait Trait) Repr() string {
 	case Div:
 		return "/"
 	case Mod:
-		return "*"
+		return "%"
 	case BinNot:
 		return "~"
 	case BinAnd:
@@ -219,6 +285,33 @@ func (trait Trait) Repr() string {
 		return ";"
 
 	default:
+		traitsLock.RLock()
+		defer traitsLock.RUnlock()
+		if i := int(trait - Semicolon - 1); trait > Semicolon && i < len(traitNames) {
+			return traitNames[i]
+		}
 		return "?"
 	}
 }
+
+func (trait Trait) Invalid() bool {
+	return trait >= InvalidChar && trait <= MalformedNumber
+}
+
+// Message of the lexical errors
+func (trait Trait) Reason() string {
+	switch trait {
+	case InvalidChar:
+		return "Invalid character"
+	case UnterminatedStr:
+		return "Unterminated string literal"
+	case UnterminatedChar:
+		return "Unterminated character literal"
+	case UnterminatedComment:
+		return "Unterminated block comment"
+	case MalformedNumber:
+		return "Malformed number literal"
+	default:
+		return ""
+	}
+}